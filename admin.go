@@ -0,0 +1,120 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// errNoLabelsStore is returned by the admin handlers if no labels store is set.
+var errNoLabelsStore = errors.New("the entity labels store is not set")
+
+// labelsSolution defines the payload of the entity labels list.
+type labelsSolution struct {
+	TimeData
+	Data []*labels.Label
+}
+
+// labelSolution defines the payload of a single address entity label.
+type labelSolution struct {
+	TimeData
+	Data *labels.Label
+}
+
+// labelRequest defines the payload expected by the label update endpoint. The
+// address is set in the request path.
+type labelRequest struct {
+	Entity   string          `json:"entity"`
+	Category labels.Category `json:"category"`
+}
+
+// LabelsHandler returns all the entity labels sorted by address.
+func (exp *explorer) LabelsHandler(w http.ResponseWriter, r *http.Request) {
+	t := time.Now()
+
+	if exp.Labels == nil {
+		exp.StatusHandler(w, r, t, errNoLabelsStore)
+		return
+	}
+
+	exp.handleJSONWrite(
+		labelsSolution{
+			Data:     exp.Labels.List(),
+			TimeData: TimeData{Duration: durationInSec(t)},
+		},
+		http.StatusOK, t, w, r)
+}
+
+// LabelHandler returns, sets or removes the entity label of the address in the
+// request path depending on the request method. The removed label is returned
+// on removal.
+func (exp *explorer) LabelHandler(w http.ResponseWriter, r *http.Request) {
+	t := time.Now()
+
+	if exp.Labels == nil {
+		exp.StatusHandler(w, r, t, errNoLabelsStore)
+		return
+	}
+
+	address := mux.Vars(r)["address"]
+
+	var label *labels.Label
+	var err error
+
+	switch r.Method {
+	case http.MethodPut:
+		label, err = exp.putLabel(w, r, address)
+
+	case http.MethodDelete:
+		label, err = exp.Labels.Get(address)
+		if err == nil {
+			err = exp.Labels.Delete(address)
+		}
+
+	default:
+		label, err = exp.Labels.Get(address)
+	}
+
+	if err != nil {
+		exp.StatusHandler(w, r, t, err)
+		return
+	}
+
+	exp.handleJSONWrite(
+		labelSolution{
+			Data:     label,
+			TimeData: TimeData{Duration: durationInSec(t)},
+		},
+		http.StatusOK, t, w, r)
+}
+
+// putLabel decodes the label update request payload and sets the entity label
+// of the address.
+func (exp *explorer) putLabel(w http.ResponseWriter, r *http.Request,
+	address string) (*labels.Label, error) {
+	var req labelRequest
+	body := http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			fmt.Sprintf("invalid request payload: %v", err))
+	}
+
+	label := labels.Label{
+		Address:  address,
+		Entity:   req.Entity,
+		Category: req.Category,
+	}
+
+	if err := exp.Labels.Put(label); err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
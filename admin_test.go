@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/analytics"
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
+)
+
+// TestLabelsAdmin tests the entity labels admin routes and that they require
+// an admin key.
+func TestLabelsAdmin(t *testing.T) {
+	type testData struct {
+		Method   string
+		Path     string
+		Key      string
+		Body     string
+		Status   int
+		Expected string
+	}
+
+	td := []testData{
+		{Method: "GET", Path: "/api/v1/admin/labels", Status: http.StatusUnauthorized},
+		{Method: "GET", Path: "/api/v1/admin/labels", Key: "key-a", Status: http.StatusUnauthorized},
+		{Method: "GET", Path: "/api/v1/admin/labels", Key: "admin", Status: http.StatusOK,
+			Expected: `[{"Address":"TsVSP","Entity":"VSP A","Category":"vsp"}]`},
+		{Method: "PUT", Path: "/api/v1/admin/labels/TsEx", Key: "admin",
+			Body: `{"entity":"Exchange B","category":"exchange"}`, Status: http.StatusOK,
+			Expected: `{"Address":"TsEx","Entity":"Exchange B","Category":"exchange"}`},
+		{Method: "PUT", Path: "/api/v1/admin/labels/TsEx", Key: "admin",
+			Body: `{"entity":"Exchange B","category":"bank"}`, Status: http.StatusBadRequest},
+		{Method: "PUT", Path: "/api/v1/admin/labels/TsEx", Key: "admin", Body: `{`,
+			Status: http.StatusBadRequest},
+		{Method: "GET", Path: "/api/v1/admin/labels/TsEx", Key: "admin", Status: http.StatusOK,
+			Expected: `{"Address":"TsEx","Entity":"Exchange B","Category":"exchange"}`},
+		{Method: "DELETE", Path: "/api/v1/admin/labels/TsVSP", Key: "admin",
+			Status: http.StatusBadRequest},
+		{Method: "DELETE", Path: "/api/v1/admin/labels/TsEx", Key: "admin", Status: http.StatusOK,
+			Expected: `{"Address":"TsEx","Entity":"Exchange B","Category":"exchange"}`},
+		{Method: "GET", Path: "/api/v1/admin/labels/TsEx", Key: "admin",
+			Status: http.StatusNotFound},
+	}
+
+	dir, err := ioutil.TempDir("", "labels")
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "vsps.csv"), []byte("TsVSP,VSP A,vsp\n"), 0600)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	exp := newTestExplorer()
+	exp.Labels, err = labels.NewStore(dir)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	exp.Auth = newAPIAuth(&config{APIKeys: []string{"key-a"}, AdminKeys: []string{"admin"}})
+
+	router := newRouter(exp)
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest(data.Method, data.Path, strings.NewReader(data.Body))
+			if data.Key != "" {
+				req.Header.Set(apiKeyHeader, data.Key)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != data.Status {
+				t.Fatalf("expected status %d but found %d: %s", data.Status, w.Code,
+					w.Body.String())
+			}
+
+			if data.Expected == "" {
+				return
+			}
+
+			var payload struct{ Data json.RawMessage }
+			if err := json.Unmarshal(w.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("expected a JSON payload but found %s", w.Body.String())
+			}
+
+			if string(payload.Data) != data.Expected {
+				t.Fatalf("expected data %s but found %s", data.Expected, string(payload.Data))
+			}
+		})
+	}
+}
+
+// TestLabeledAnalysis tests that the labels set through the admin API are
+// applied to the tx analysis of the v1 and v2 routes returning labels.
+func TestLabeledAnalysis(t *testing.T) {
+	dir, err := ioutil.TempDir("", "labels")
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	exp := newTestExplorer()
+	exp.Labels, err = labels.NewStore(dir)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	err = exp.Labels.Put(labels.Label{Address: "TsVRiAC6S25a7zL1frwbt2GcNg8YgojPZ3V",
+		Entity: "Exchange A", Category: labels.CategoryExchange})
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	w := serve(exp, "GET", "/api/v1/"+replayTxID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d but found %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var payload probabilitySolution
+	if err = json.Unmarshal(w.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("expected a JSON payload but found %s", w.Body.String())
+	}
+
+	if payload.Labels == nil || len(payload.Labels.Outputs) != 1 ||
+		payload.Labels.Outputs[0].Index != 3 ||
+		payload.Labels.Outputs[0].Labels[0].Entity != "Exchange A" {
+		t.Fatalf("expected output 3 to be labeled but found %+v", payload.Labels)
+	}
+
+	w = serve(exp, "GET", "/api/v2/tx/"+replayTxID, nil)
+
+	var v2Payload struct {
+		Data struct {
+			Labels *analytics.TxLabels `json:"labels"`
+		} `json:"data"`
+	}
+	if err = json.Unmarshal(w.Body.Bytes(), &v2Payload); err != nil {
+		t.Fatalf("expected a JSON payload but found %s", w.Body.String())
+	}
+
+	if v2Payload.Data.Labels == nil || len(v2Payload.Data.Labels.Outputs) != 1 {
+		t.Fatalf("expected output 3 to be labeled but found %s", w.Body.String())
+	}
+}
+
+// TestAdminDisabled tests that the admin routes are rejected if no admin key
+// is set.
+func TestAdminDisabled(t *testing.T) {
+	exp := newTestExplorer()
+
+	if w := serve(exp, "GET", "/api/v1/admin/labels", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d but found %d: %s", http.StatusUnauthorized, w.Code,
+			w.Body.String())
+	}
+}
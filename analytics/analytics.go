@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/decred/slog"
 	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
@@ -35,7 +36,9 @@ func TransactionFundsFlow(tx *rpcutils.Transaction) ([]*AllFundsFlows,
 
 	// granularBuckets are buckets whose inputs and outputs are split to the
 	// minimum possible values.
+	t := time.Now()
 	granularBuckets, inputs, outputs := getPrefabricatedBuckets(originalInputs, originalOutputs)
+	observePhase(phasePrefabBuckets, t)
 
 	if setLog <= slog.LevelInfo {
 		log.Infof("Found %d prefabricated granular buckets from inputs and outputs",
@@ -44,6 +47,8 @@ func TransactionFundsFlow(tx *rpcutils.Transaction) ([]*AllFundsFlows,
 
 	// If tx is complex exit
 	if isTxComplex(inputs, outputs) {
+		complexTxs.Inc()
+
 		if setLog <= slog.LevelInfo {
 			log.Infof("Complex tx %s could not be analyzed", tx.TxID)
 		}
@@ -57,16 +62,19 @@ func TransactionFundsFlow(tx *rpcutils.Transaction) ([]*AllFundsFlows,
 		log.Info("Calculating all possible sum combinations for both inputs and outputs")
 	}
 
+	t = time.Now()
 	inputCombinations := getTotalCombinations(inputs, inpointData, true)
 	outputCombinations := getTotalCombinations(outputs, outpointData, true)
+	observePhase(phaseCombinations, t)
 
-	// drop doping element entry if it exists.
+	// drop doping element entry if it exists. The prefabricated buckets may
+	// have matched all the inputs or outputs.
 	{
-		if inputs[len(inputs)-1] == dopingElement {
+		if len(inputs) > 0 && inputs[len(inputs)-1] == dopingElement {
 			inputs = inputs[:len(inputs)-1]
 		}
 
-		if outputs[len(outputs)-1] == dopingElement {
+		if len(outputs) > 0 && outputs[len(outputs)-1] == dopingElement {
 			outputs = outputs[:len(outputs)-1]
 		}
 	}
@@ -75,21 +83,31 @@ func TransactionFundsFlow(tx *rpcutils.Transaction) ([]*AllFundsFlows,
 		log.Info("Adding the outputs sums combination list to the binary tree.")
 	}
 
+	t = time.Now()
 	defBinaryTree := new(Node)
 	if err := defBinaryTree.Insert(outputCombinations); err != nil {
 		return nil, inputs, outputs,
 			fmt.Errorf("Inserting the sums combinations to the binary tree failed: %v", err)
 	}
+	observePhase(phaseTreeInsert, t)
 
 	if setLog <= slog.LevelInfo {
 		log.Info("Searching for matching sums between inputs and outputs amounts.")
 	}
 
+	t = time.Now()
 	matchedSum := defBinaryTree.FindX(inputCombinations, tx.Fees)
+	observePhase(phaseFindX, t)
+
+	// Drop the matched buckets whose fees the fee model does not expect.
+	model := feeModel
+	matchedSum = model.filterBuckets(matchedSum, tx.Fees, len(inputs))
+
 	if setLog <= slog.LevelInfo {
 		log.Info("Matching the inputs and outputs selected to generate a solution(s)")
 	}
 
+	t = time.Now()
 	solutionsChan := make(chan []*AllFundsFlows)
 	// getSolutions runs on a different goroutine to avoid blocking the main goroutine.
 	go func() {
@@ -99,6 +117,11 @@ func TransactionFundsFlow(tx *rpcutils.Transaction) ([]*AllFundsFlows,
 
 	txSolutions := <-solutionsChan
 	close(solutionsChan)
+	observePhase(phaseGetSolutions, t)
+
+	// The buckets split from the matched buckets may not fit the fee model.
+	txSolutions = model.filterSolutions(txSolutions, tx.Fees, len(inputs))
+	solutionsCount.Observe(float64(len(txSolutions)))
 
 	// ensures that matched solutions count starts from 1 always.
 	for i, val := range txSolutions {
@@ -111,20 +134,25 @@ func TransactionFundsFlow(tx *rpcutils.Transaction) ([]*AllFundsFlows,
 			len(txSolutions))
 	}
 
-	// If no matching solution(s) was found then the tx is possibly resolved by default.
+	// If no matching solution(s) was found then the tx is possibly resolved by
+	// default i.e. the inputs left after the prefabricated buckets are matched
+	// fund all the outputs left.
 	if len(txSolutions) == 0 {
+		var fundsFlow []TxFundsFlow
+		if len(inputs) > 0 || len(outputs) > 0 {
+			fundsFlow = append(fundsFlow, TxFundsFlow{
+				Fee:            tx.Fees,
+				Inputs:         getGroupedValues(inputs),
+				MatchedOutputs: getGroupedValues(outputs),
+			})
+		}
+
 		txSolutions = append(txSolutions, &AllFundsFlows{
 			// Solution 0, implies that the code failed to get any matches and
 			// thus returned the default solution instead of null.
 			Solution:  0,
 			TotalFees: tx.Fees,
-			FundsFlow: []TxFundsFlow{
-				{
-					Fee:            tx.Fees,
-					Inputs:         getGroupedValues(originalInputs),
-					MatchedOutputs: getGroupedValues(originalOutputs),
-				},
-			},
+			FundsFlow: append(fundsFlow, granularBuckets...),
 		})
 	}
 	return txSolutions, originalInputs, originalOutputs, nil
@@ -299,5 +327,21 @@ func TxFundsFlowProbability(rawData []*AllFundsFlows,
 		data = append(data, ss)
 	}
 
+	// Sort the outputs by their amounts since the map iteration order changes.
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].OutputAmount < data[j].OutputAmount
+	})
+
 	return data
 }
+
+// TooComplexError returns an ErrTxTooComplex error if the status message of
+// the tx solutions reports that the tx is too complex to be analyzed.
+func TooComplexError(txID, statusMsg string) error {
+	if statusMsg != complexTxMsg {
+		return nil
+	}
+
+	return rpcutils.NewError(rpcutils.ErrTxTooComplex,
+		fmt.Sprintf("transaction %s is too complex to be analyzed", txID))
+}
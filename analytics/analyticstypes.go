@@ -2,6 +2,8 @@ package analytics
 
 import (
 	"sync"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
 )
 
 const (
@@ -40,11 +42,27 @@ type Node struct {
 // other Matched Hub(s). A chain of hubs provide the flow of funds from the current
 // output TxHash to back in time where the source of funds can be identified.
 type Hub struct {
-	// Unique details of the current output.
-	address string
-	Amount  float64
-	TxHash  string
-	Vout    uint32
+	// Unique details of the current output. Addresses holds all the
+	// addresses the output script pays to.
+	Addresses []string `json:",omitempty"`
+	Amount    float64
+	TxHash    string
+	Vout      uint32
+
+	// Script metadata of the current output. ScriptType is the script class
+	// and StakeSubclass the class of the script tagged by a stake opcode.
+	ScriptType    string `json:",omitempty"`
+	ReqSigs       int32  `json:",omitempty"`
+	StakeSubclass string `json:",omitempty"`
+
+	// Labels lists the entity labels of the output addresses.
+	Labels []*labels.Label `json:",omitempty"`
+
+	// nullData marks a provably unspendable output that ends the chain.
+	nullData bool
+
+	// duplicates is the number of the tx outputs with the output amount.
+	duplicates int
 
 	// Probability Types
 	PathProbability  float64 `json:",omitempty"`
@@ -52,6 +70,10 @@ type Hub struct {
 
 	StatusMsg string `json:",omitempty"`
 
+	// Privacy rates the output privacy. It is only set on the chain root hubs
+	// if the privacy scores were requested.
+	Privacy *PrivacyScore `json:",omitempty"`
+
 	// setCount helps track which set whose entry has already been processed
 	// in a specific Hub.
 	setCount int
@@ -128,8 +150,29 @@ type FlowProbability struct {
 	uniqueInputs       map[float64]int
 }
 
+// TxAnalysis groups together the fee details, the funds flow solutions, the
+// funds flow probabilities, the deterministic links, the inputs to outputs
+// link matrix, the entropy metrics, the ticket details and the entity labels
+// generated from a single transaction. Ticket is only set for the tickets.
+// Labels is only set if the tx inputs and outputs entity labels were requested
+// and some are labeled. Explanation is only set if the plain language
+// explanation of the analysis was requested.
+type TxAnalysis struct {
+	TxID               string
+	Fee                *TxFee `json:",omitempty"`
+	Solutions          []*AllFundsFlows
+	Probabilities      []*FlowProbability
+	DeterministicLinks []*DeterministicLink `json:",omitempty"`
+	LinkMatrix         *LinkMatrix          `json:",omitempty"`
+	Entropy            *TxEntropy           `json:",omitempty"`
+	Ticket             *TicketAnalysis      `json:",omitempty"`
+	Labels             *TxLabels            `json:",omitempty"`
+	Explanation        []string             `json:",omitempty"`
+}
+
 // custom sort interface that sorts by Possible inputs in the probability set
-// data.
+// data. Entries with the same possible inputs are sorted by their amounts to
+// keep the order deterministic.
 type byPossibleInputs []*Details
 
 func (s byPossibleInputs) Len() int {
@@ -141,5 +184,8 @@ func (s byPossibleInputs) Swap(i, j int) {
 }
 
 func (s byPossibleInputs) Less(i, j int) bool {
+	if s[i].PossibleInputs == s[j].PossibleInputs {
+		return s[i].Amount < s[j].Amount
+	}
 	return s[i].PossibleInputs > s[j].PossibleInputs
 }
@@ -0,0 +1,172 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"sort"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// AncestryHop is an output on the path from a traced output to an ancestor.
+type AncestryHop struct {
+	TxHash string
+	Vout   uint32
+	Amount float64
+}
+
+// AncestryPath is the path from a traced output, its first hop, to an
+// ancestor, its last hop. Probability is the product of the linking
+// probabilities of the outputs funded along the path.
+type AncestryPath struct {
+	Hops        []*AncestryHop
+	Probability float64
+}
+
+// SharedAncestor is an output found in the backward traces of both outputs.
+// PathA and PathB are the most probable paths to it from each output and
+// Probability the probability of both paths, i.e. of the ancestor funding
+// both outputs.
+type SharedAncestor struct {
+	TxHash      string
+	Vout        uint32
+	Addresses   []string `json:",omitempty"`
+	Amount      float64
+	PathA       *AncestryPath
+	PathB       *AncestryPath
+	Probability float64
+}
+
+// CommonAncestry holds the ancestors shared by the outputs A and B, formatted
+// as "txhash:vout", most probable first.
+type CommonAncestry struct {
+	A         string
+	B         string
+	Ancestors []*SharedAncestor
+}
+
+// ancestor is an output reached by a backward trace with the most probable
+// path to it.
+type ancestor struct {
+	hub  *Hub
+	path *AncestryPath
+}
+
+// DefaultAncestryDepth is the depth the common ancestry traces stop at if no
+// max depth is set. It is lower than DefaultTraceDepth since both outputs are
+// traced through all their probable funding sets.
+const DefaultAncestryDepth = 4
+
+// FindCommonAncestry traces both outputs back as defined by the options and
+// returns the ancestors found in both traces. The traces follow the certain
+// links, as a link to a single funding set is no source of funds for the
+// ancestry, thus they stop at the options max depth or at
+// DefaultAncestryDepth. The outputs are formatted as "txhash:vout". An
+// ErrInvalidRequest error is returned if either is invalid.
+func FindCommonAncestry(client rpcutils.TxSource, a, b string, opts ChainOptions) (
+	*CommonAncestry, error) {
+	opts.FollowCertain = true
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultAncestryDepth
+	}
+
+	outpoints := make([]outpoint, 2)
+	for i, s := range []string{a, b} {
+		op, err := parseOutpoint(s)
+		if err != nil {
+			return nil, err
+		}
+		outpoints[i] = op
+	}
+
+	roots := make([]*Hub, 2)
+	for i, op := range outpoints {
+		chain, _, err := ChainDiscoveryWithOptions(client, op.txHash, opts, int(op.vout))
+		if err != nil {
+			return nil, err
+		}
+		roots[i] = chain[0]
+	}
+
+	return &CommonAncestry{
+		A:         a,
+		B:         b,
+		Ancestors: SharedAncestors(roots[0], roots[1]),
+	}, nil
+}
+
+// SharedAncestors returns the outputs found in both chains, the chain root
+// outputs included, most probable first. The coinbase and stakebase inputs are
+// skipped since they are not outputs.
+func SharedAncestors(rootA, rootB *Hub) []*SharedAncestor {
+	ancestorsA := make(map[outpoint]*ancestor)
+	collectAncestors(rootA, nil, 1, ancestorsA)
+
+	ancestorsB := make(map[outpoint]*ancestor)
+	collectAncestors(rootB, nil, 1, ancestorsB)
+
+	shared := make([]*SharedAncestor, 0)
+	for op, a := range ancestorsA {
+		b, ok := ancestorsB[op]
+		if !ok {
+			continue
+		}
+
+		shared = append(shared, &SharedAncestor{
+			TxHash:      op.txHash,
+			Vout:        op.vout,
+			Addresses:   a.hub.Addresses,
+			Amount:      a.hub.Amount,
+			PathA:       a.path,
+			PathB:       b.path,
+			Probability: roundOff(a.path.Probability * b.path.Probability),
+		})
+	}
+
+	sort.Slice(shared, func(i, j int) bool {
+		x, y := shared[i], shared[j]
+		if x.Probability == y.Probability {
+			if x.TxHash == y.TxHash {
+				return x.Vout < y.Vout
+			}
+			return x.TxHash < y.TxHash
+		}
+		return x.Probability > y.Probability
+	})
+
+	return shared
+}
+
+// collectAncestors records the hub and all the hubs funding it with the most
+// probable path to each of them. path holds the hops before the hub and
+// probability the probability of the hub funding the traced output. The
+// shortest path is kept if several are as probable.
+func collectAncestors(h *Hub, path []*AncestryHop, probability float64,
+	ancestors map[outpoint]*ancestor) {
+	if h.TxHash == "" {
+		return
+	}
+
+	hops := make([]*AncestryHop, len(path), len(path)+1)
+	copy(hops, path)
+	hops = append(hops, &AncestryHop{TxHash: h.TxHash, Vout: h.Vout, Amount: h.Amount})
+
+	p := &AncestryPath{Hops: hops, Probability: roundOff(probability)}
+
+	op := outpoint{txHash: h.TxHash, vout: h.Vout}
+	a, ok := ancestors[op]
+	switch {
+	case !ok:
+		ancestors[op] = &ancestor{hub: h, path: p}
+	case p.Probability > a.path.Probability ||
+		(p.Probability == a.path.Probability && len(p.Hops) < len(a.path.Hops)):
+		a.path = p
+	}
+
+	for _, set := range h.Matched {
+		for _, input := range set.Inputs {
+			collectAncestors(input, hops, probability*h.LevelProbability, ancestors)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package analytics
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// TestSharedAncestors tests that the ancestors found in both chains are
+// returned with the most probable path from each chain root.
+func TestSharedAncestors(t *testing.T) {
+	type testData struct {
+		TxHash      string
+		PathA       []string
+		PathB       []string
+		Probability float64
+	}
+
+	// The shared output s is reached by root a through x or y and by root b
+	// directly. The shared output z is only reached by root a through y.
+	shared := &Hub{TxHash: "s", Amount: 5}
+	rootA := &Hub{TxHash: "a", Amount: 3, LevelProbability: 0.5, Matched: []Set{
+		{Inputs: []*Hub{{TxHash: "x", Amount: 4, LevelProbability: 0.5,
+			Matched: []Set{{Inputs: []*Hub{shared}}}}}},
+		{Inputs: []*Hub{{TxHash: "y", Amount: 4, LevelProbability: 1,
+			Matched: []Set{{Inputs: []*Hub{shared, {TxHash: "z", Amount: 1}}}}}}},
+	}}
+	rootB := &Hub{TxHash: "b", Amount: 2, LevelProbability: 1, Matched: []Set{
+		{Inputs: []*Hub{shared, {TxHash: "y", Vout: 1, Amount: 1}, {TxHash: ""}}},
+	}}
+
+	td := []testData{
+		{TxHash: "s", PathA: []string{"a", "y", "s"}, PathB: []string{"b", "s"},
+			Probability: 0.5},
+	}
+
+	ancestors := SharedAncestors(rootA, rootB)
+	if len(ancestors) != len(td) {
+		t.Fatalf("expected %d shared ancestors but found %d", len(td), len(ancestors))
+	}
+
+	hashes := func(p *AncestryPath) []string {
+		var h []string
+		for _, hop := range p.Hops {
+			h = append(h, hop.TxHash)
+		}
+		return h
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			a := ancestors[i]
+			if a.TxHash != data.TxHash || a.Probability != data.Probability {
+				t.Fatalf("expected the ancestor %s with probability %v but found %s with %v",
+					data.TxHash, data.Probability, a.TxHash, a.Probability)
+			}
+
+			if !reflect.DeepEqual(hashes(a.PathA), data.PathA) ||
+				!reflect.DeepEqual(hashes(a.PathB), data.PathB) {
+				t.Fatalf("expected the paths %v and %v but found %v and %v", data.PathA,
+					data.PathB, hashes(a.PathA), hashes(a.PathB))
+			}
+		})
+	}
+}
+
+// TestFindCommonAncestry tests the common ancestry of the recorded tx outputs.
+func TestFindCommonAncestry(t *testing.T) {
+	client := rpcutils.NewReplayer(replayDir)
+
+	ancestry, err := FindCommonAncestry(client, replayTxID+":1", replayTxID+":2",
+		ChainOptions{MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("expected no error to be returned but found %v", err)
+	}
+
+	checkGolden(t, "common_ancestry", ancestry)
+
+	_, err = FindCommonAncestry(client, replayTxID+":1", replayTxID, ChainOptions{})
+	if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != rpcutils.ErrInvalidRequest {
+		t.Fatalf("expected an invalid request error but found %v", err)
+	}
+}
+
+// TestCommonAncestryPastCertainLink tests that the ancestry of outputs
+// certainly linked to their inputs is traced past these links.
+func TestCommonAncestryPastCertainLink(t *testing.T) {
+	ancestry, err := FindCommonAncestry(labeledSource(), labeledTxHash+":0",
+		labeledTxHash+":1", ChainOptions{})
+	if err != nil {
+		t.Fatalf("expected no error to be returned but found %v", err)
+	}
+
+	var shared []string
+	for _, a := range ancestry.Ancestors {
+		if a.Probability != 1 {
+			t.Fatalf("expected the certain ancestor %s:%d but found a %v probability",
+				a.TxHash, a.Vout, a.Probability)
+		}
+		shared = append(shared, a.TxHash)
+	}
+
+	expected := []string{exchangeTxHash, depositTxHash}
+	if !reflect.DeepEqual(shared, expected) {
+		t.Fatalf("expected the shared ancestors %v but found %v", expected, shared)
+	}
+}
+
+// TestCommonAncestryDefaultDepth tests that the ancestry traces stop at
+// DefaultAncestryDepth if no max depth is set.
+func TestCommonAncestryDefaultDepth(t *testing.T) {
+	hash := func(i int) string {
+		return fmt.Sprintf("%064x", i+1)
+	}
+
+	// Both outputs of the first tx are funded by a line of txs each spending
+	// the single output of the next one.
+	client := mapSource{
+		hash(0): {
+			Txid: hash(0),
+			Vin:  []dcrjson.Vin{{Txid: hash(1), Vout: 0, AmountIn: 2}},
+			Vout: []dcrjson.Vout{{Value: 1, N: 0}, {Value: 0.99, N: 1}},
+		},
+	}
+	for i := 1; i < 2*DefaultTraceDepth; i++ {
+		client[hash(i)] = &dcrjson.TxRawResult{
+			Txid: hash(i),
+			Vin:  []dcrjson.Vin{{Txid: hash(i + 1), Vout: 0, AmountIn: 2}},
+			Vout: []dcrjson.Vout{{Value: 2, N: 0}},
+		}
+	}
+
+	ancestry, err := FindCommonAncestry(client, hash(0)+":0", hash(0)+":1", ChainOptions{})
+	if err != nil {
+		t.Fatalf("expected no error to be returned but found %v", err)
+	}
+
+	// The outputs at the max depth list their matched inputs.
+	if len(ancestry.Ancestors) != DefaultAncestryDepth {
+		t.Fatalf("expected %d shared ancestors but found %d", DefaultAncestryDepth,
+			len(ancestry.Ancestors))
+	}
+}
@@ -6,13 +6,12 @@ package analytics
 import (
 	"fmt"
 
-	"github.com/decred/dcrd/rpcclient"
 	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
 )
 
 // RetrieveTxData fetches a transaction data from the rpc client returns
 // processed transaction data.
-func RetrieveTxData(client *rpcclient.Client, txHash string) (*rpcutils.Transaction, error) {
+func RetrieveTxData(client rpcutils.TxSource, txHash string) (*rpcutils.Transaction, error) {
 	// Return an empty Transactions object if txHash used is empty.
 	if txHash == "" {
 		return &rpcutils.Transaction{}, nil
@@ -22,14 +21,14 @@ func RetrieveTxData(client *rpcclient.Client, txHash string) (*rpcutils.Transact
 
 	txData, err := rpcutils.GetTransactionVerboseByID(client, txHash)
 	if err != nil {
-		return nil, fmt.Errorf("RetrieveTxData error: failed to fetch transaction %s", txHash)
+		return nil, err
 	}
 
 	return rpcutils.ExtractRawTxTransaction(txData), nil
 }
 
 // RetrieveTxProbability returns the tx level probability values for each output.
-func RetrieveTxProbability(client *rpcclient.Client, txHash string) (
+func RetrieveTxProbability(client rpcutils.TxSource, txHash string) (
 	[]*FlowProbability, *rpcutils.Transaction, error) {
 	tx, err := RetrieveTxData(client, txHash)
 	if err != nil {
@@ -44,11 +43,85 @@ func RetrieveTxProbability(client *rpcclient.Client, txHash string) (
 	return TxFundsFlowProbability(rawSolution, inputs, outputs), tx, nil
 }
 
+// RetrieveTxAnalysis returns the funds flow analysis of the tx.
+func RetrieveTxAnalysis(client rpcutils.TxSource, txHash string) (
+	*TxAnalysis, *rpcutils.Transaction, error) {
+	tx, err := RetrieveTxData(client, txHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	analysis, err := AnalyzeTransaction(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return analysis, tx, nil
+}
+
+// DefaultTraceDepth is the depth the chain discovery stops at if only the
+// deterministic links or all the certain links are followed and no max depth
+// is set.
+const DefaultTraceDepth = 10
+
+// ChainOptions defines how the funds flow paths are discovered. The zero value
+// follows all the probable links until the source of funds is identified.
+type ChainOptions struct {
+	// DeterministicOnly follows only the links found in every valid funds flow
+	// solution of each tx until no deterministic link is left or, if MaxDepth
+	// is not set, until DefaultTraceDepth.
+	DeterministicOnly bool
+
+	// FollowCertain keeps following the funds past the certain links, whose
+	// level probability is 1, instead of taking the first one as the source of
+	// funds. The discovery stops at MaxDepth or, if not set, at
+	// DefaultTraceDepth.
+	FollowCertain bool
+
+	// MaxDepth stops the discovery at the depth provided if greater than zero.
+	// The output hubs are at depth 1 and the hubs at the max depth list their
+	// matched inputs without analyzing them further.
+	MaxDepth int
+
+	// Labels annotates the hubs with the entity labels of their addresses if
+	// set.
+	Labels Labeler
+
+	// StopAtLabels stops the discovery at the hubs paying to a labeled address
+	// other than the output hubs since tracing the funds past a known entity,
+	// such as an exchange, is meaningless. It has no effect if Labels is not
+	// set.
+	StopAtLabels bool
+
+	// Privacy sets the privacy score of the output hubs. Looking up their
+	// source of funds may fetch more txs past the chain discovered.
+	Privacy bool
+}
+
 // ChainDiscovery returns all the possible chains associated with the tx hash used.
-func ChainDiscovery(client *rpcclient.Client, txHash string, outputIndex ...int) ([]*Hub, int64, error) {
+// An ErrInvalidOutputIndex error is returned if the tx has no output at the
+// output index provided.
+func ChainDiscovery(client rpcutils.TxSource, txHash string, outputIndex ...int) ([]*Hub, int64, error) {
+	return ChainDiscoveryWithOptions(client, txHash, ChainOptions{}, outputIndex...)
+}
+
+// ChainDiscoveryWithOptions returns the chains associated with the tx hash used
+// discovered as defined by the options. An ErrInvalidRequest error is returned
+// if the options are invalid.
+func ChainDiscoveryWithOptions(client rpcutils.TxSource, txHash string, opts ChainOptions,
+	outputIndex ...int) ([]*Hub, int64, error) {
+	if opts.MaxDepth < 0 {
+		return nil, 0, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			"the chain max depth should not be negative")
+	}
+
+	if (opts.DeterministicOnly || opts.FollowCertain) && opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultTraceDepth
+	}
+
 	tx, err := RetrieveTxData(client, txHash)
 	if err != nil {
-		return nil, tx.BlockTime, err
+		return nil, 0, err
 	}
 
 	// hubsChain defines the various paths with funds flows from a given output to
@@ -60,13 +133,10 @@ func ChainDiscovery(client *rpcclient.Client, txHash string, outputIndex ...int)
 	switch {
 	// OutputIndex has been provided
 	case len(outputIndex) > 0:
-		var txIndex int
-
-		if outputIndex[0] > len(tx.Outpoints)-1 {
-			txIndex = len(tx.Outpoints) - 1
-
-		} else if outputIndex[0] > 0 {
-			txIndex = outputIndex[0]
+		txIndex := outputIndex[0]
+		if txIndex < 0 || txIndex >= len(tx.Outpoints) {
+			return nil, tx.BlockTime, rpcutils.NewError(rpcutils.ErrInvalidOutputIndex,
+				fmt.Sprintf("transaction %s has no output at index %d", tx.TxID, txIndex))
 		}
 
 		outPoints = append(outPoints, tx.Outpoints[txIndex])
@@ -83,17 +153,29 @@ func ChainDiscovery(client *rpcclient.Client, txHash string, outputIndex ...int)
 		pathOdds, pathPOI := 1.0, 1.0
 
 		entry := &Hub{
-			TxHash:  tx.TxID,
-			Amount:  val.Value,
-			Vout:    val.TxIndex,
-			address: val.PkScriptData.Addresses[0],
+			TxHash: tx.TxID,
+			Amount: val.Value,
+			Vout:   val.TxIndex,
 		}
+		entry.setScript(val.PkScriptData)
+		entry.setLabels(opts.Labels)
 
-		err = handleDepths(entry, stackTrace, client, count, pathOdds, pathPOI)
+		err = handleDepths(entry, stackTrace, client, opts, count, pathOdds, pathPOI)
 		if err != nil {
 			return nil, tx.BlockTime, err
 		}
 
+		if opts.Privacy {
+			entry.Privacy, err = OutputPrivacy(client, entry, opts.Labels)
+			if err != nil {
+				return nil, tx.BlockTime, err
+			}
+		}
+
+		depth, hubs := hubStats(entry)
+		chainDepth.Observe(float64(depth))
+		chainHubs.Observe(float64(hubs))
+
 		hubsChain = append(hubsChain, entry)
 	}
 
@@ -104,11 +186,14 @@ func ChainDiscovery(client *rpcclient.Client, txHash string, outputIndex ...int)
 
 // handleDepths recusively creates a graph-like data structure that shows the
 // funds flow path from output (UTXO) to the source of funds at the provided depth.
-// totalOdds defines the effective path probability at the current depth.
-func handleDepths(curHub *Hub, stack []*Hub, client *rpcclient.Client, count int,
-	totalOdds, pathPOI float64) error {
-	err := curHub.getDepth(client, pathPOI)
-	if err != nil {
+// totalOdds defines the effective path probability at the current depth and
+// count the current depth.
+func handleDepths(curHub *Hub, stack []*Hub, client rpcutils.TxSource, opts ChainOptions,
+	count int, totalOdds, pathPOI float64) error {
+	// Only the output hubs are analyzed if they pay to a labeled entity.
+	if opts.StopAtLabels && count > 1 && len(curHub.Labels) > 0 {
+		curHub.StatusMsg = "Stopped at a labeled entity"
+	} else if err := curHub.getDepth(client, opts, pathPOI); err != nil {
 		return err
 	}
 
@@ -117,9 +202,15 @@ func handleDepths(curHub *Hub, stack []*Hub, client *rpcclient.Client, count int
 		curHub.PathProbability = totalOdds
 	}
 
+	// The source of funds is identified once a link is certain unless only
+	// the deterministic links or all the certain links are followed.
+	isIdentified := curHub.LevelProbability == 1 && !opts.DeterministicOnly &&
+		!opts.FollowCertain
+	isMaxDepth := opts.MaxDepth > 0 && count >= opts.MaxDepth
+
 	// backtrack till we find an unprocessed Hub. LevelProbability should lie
 	// between 1 and 0.
-	if curHub.LevelProbability == 1 || curHub.PathProbability == 0 ||
+	if isIdentified || isMaxDepth || curHub.PathProbability == 0 ||
 		curHub.TxHash == "" || curHub.StatusMsg != "" {
 		if curHub.LevelProbability > 0 {
 			totalOdds = roundOff(totalOdds / curHub.LevelProbability)
@@ -153,25 +244,39 @@ func handleDepths(curHub *Hub, stack []*Hub, client *rpcclient.Client, count int
 	curHub = curHub.Matched[curHub.setCount].
 		Inputs[curHub.Matched[curHub.setCount].hubCount]
 
-	return handleDepths(curHub, stack, client, count+1, totalOdds, pathPOI)
+	return handleDepths(curHub, stack, client, opts, count+1, totalOdds, pathPOI)
 }
 
 // getDepth appends all the sets linked to a given output after a given amount
-// probability solution is resolved.
-func (h *Hub) getDepth(client *rpcclient.Client, pathPOI float64) error {
+// probability solution is resolved. Only the sets whose inputs are all
+// deterministically linked to the output are appended if the options require
+// it.
+func (h *Hub) getDepth(client rpcutils.TxSource, opts ChainOptions, pathPOI float64) error {
 	if h.TxHash == "" {
 		return nil
 	}
 
-	probabilityData, tx, err := RetrieveTxProbability(client, h.TxHash)
+	// Nulldata outputs hold no funds thus they terminate the chain.
+	if h.nullData {
+		h.StatusMsg = "Nulldata output holds no traceable funds"
+		return nil
+	}
+
+	analysis, tx, err := RetrieveTxAnalysis(client, h.TxHash)
 	if err != nil {
 		return err
 	}
 
-	for _, item := range probabilityData {
+	for _, item := range analysis.Probabilities {
 		if item.OutputAmount == h.Amount {
+			h.duplicates = item.Count
 			for _, entry := range item.ProbableInputs {
-				d, err := getSet(client, tx, entry, pathPOI)
+				if opts.DeterministicOnly &&
+					!isDeterministicSet(analysis.DeterministicLinks, entry, h.Amount) {
+					continue
+				}
+
+				d, err := getSet(client, opts, tx, entry, pathPOI)
 				if err != nil {
 					return err
 				}
@@ -188,9 +293,37 @@ func (h *Hub) getDepth(client *rpcclient.Client, pathPOI float64) error {
 	return nil
 }
 
+// setScript sets the hub script metadata from the output script data. All the
+// addresses are kept so that the co-signers of a multisig output are not lost.
+func (h *Hub) setScript(script rpcutils.ScriptPubKeyData) {
+	h.Addresses = append([]string(nil), script.Addresses...)
+	h.ScriptType = script.Type
+	h.ReqSigs = script.ReqSigs
+	h.StakeSubclass = script.StakeSubclass
+	h.nullData = script.IsNullData()
+}
+
+// setLabels sets the entity labels of the hub addresses if the labeler is set.
+func (h *Hub) setLabels(labeler Labeler) {
+	if labeler != nil {
+		h.Labels = labeler.Lookup(h.Addresses)
+	}
+}
+
+// isDeterministicSet checks if all the set inputs are deterministically linked
+// to the output amount.
+func isDeterministicSet(links []*DeterministicLink, set *InputSets, output float64) bool {
+	for _, d := range set.Set {
+		if !isDeterministic(links, d.Amount, output) {
+			return false
+		}
+	}
+	return true
+}
+
 // The sets returned in a given output probability solution does not have a lot of
 // data, this functions reconstructs the Set adding the necessary information.
-func getSet(client *rpcclient.Client, txData *rpcutils.Transaction,
+func getSet(client rpcutils.TxSource, opts ChainOptions, txData *rpcutils.Transaction,
 	matchedInputs *InputSets, pathPOI float64) (set Set, err error) {
 	inputs := make([]rpcutils.TxInput, len(txData.Inpoints))
 	copy(inputs, txData.Inpoints)
@@ -206,10 +339,11 @@ func getSet(client *rpcclient.Client, txData *rpcutils.Transaction,
 
 					s := &Hub{Amount: d.ValueIn, TxHash: d.TxHash, Vout: d.OutputTxIndex}
 
-					// fetch the current hub's Address.
+					// fetch the current hub's script data.
 					for k := range tx.Outpoints {
 						if d.OutputTxIndex == tx.Outpoints[k].TxIndex {
-							s.address = tx.Outpoints[k].PkScriptData.Addresses[0]
+							s.setScript(tx.Outpoints[k].PkScriptData)
+							s.setLabels(opts.Labels)
 							break
 						}
 					}
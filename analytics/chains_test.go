@@ -0,0 +1,209 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// replayDir holds the recorded transactions fixtures served to the tests.
+const replayDir = "../testdata/replay"
+
+// replayTxID is the recorded testnet tx whose chains are discovered.
+const replayTxID = "0fbd7e758a016c6a0b59a56eaf0ceaa562250cc44d75de810ff53ad7e1d9a231"
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares the JSON encoded data with the golden file contents.
+// The golden file is rewritten instead if the -update flag is set.
+func checkGolden(t *testing.T, name string, data interface{}) {
+	result, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		t.Fatalf("expected no error encoding the result but found %v", err)
+	}
+
+	golden := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err = ioutil.WriteFile(golden, append(result, '\n'), 0644); err != nil {
+			t.Fatalf("expected no error updating %s but found %v", golden, err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("expected no error reading %s but found %v", golden, err)
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(expected), result) {
+		t.Fatalf("expected the result to match %s but found %s", golden, string(result))
+	}
+}
+
+// TestChainDiscoveryReplay runs the chain discovery against the recorded
+// transactions and compares the chains found with the golden files.
+func TestChainDiscoveryReplay(t *testing.T) {
+	type testData struct {
+		Name        string
+		Options     ChainOptions
+		OutputIndex []int
+	}
+
+	td := []testData{
+		{Name: "chain_all", Options: ChainOptions{Privacy: true}},
+		{Name: "chain_index_1", OutputIndex: []int{1}},
+		{Name: "chain_index_3", OutputIndex: []int{3}},
+		{Name: "chain_depth_1", Options: ChainOptions{MaxDepth: 1, Privacy: true}},
+		{Name: "chain_deterministic", Options: ChainOptions{DeterministicOnly: true, MaxDepth: 2}},
+	}
+
+	client := rpcutils.NewReplayer(replayDir)
+
+	for _, data := range td {
+		t.Run(data.Name, func(t *testing.T) {
+			chain, txTime, err := ChainDiscoveryWithOptions(client, replayTxID, data.Options,
+				data.OutputIndex...)
+			if err != nil {
+				t.Fatalf("expected no error to be returned but found %v", err)
+			}
+
+			if txTime != 1631634800 {
+				t.Fatalf("expected the tx time to be 1631634800 but found %d", txTime)
+			}
+
+			checkGolden(t, data.Name, chain)
+		})
+	}
+}
+
+// TestChainDiscoveryErrors tests that the chain discovery failures return the
+// matching typed errors.
+func TestChainDiscoveryErrors(t *testing.T) {
+	type testData struct {
+		TxHash      string
+		Options     ChainOptions
+		OutputIndex []int
+		Code        rpcutils.ErrorCode
+	}
+
+	td := []testData{
+		{TxHash: "ae40333aed99c0b004ef01834444944c471bc72ce869949f6f8ced728941f561",
+			Code: rpcutils.ErrTxNotFound},
+		{TxHash: "not-a-hash", Code: rpcutils.ErrInvalidHash},
+		{TxHash: replayTxID, OutputIndex: []int{10}, Code: rpcutils.ErrInvalidOutputIndex},
+		{TxHash: replayTxID, Options: ChainOptions{MaxDepth: -1}, Code: rpcutils.ErrInvalidRequest},
+	}
+
+	client := rpcutils.NewReplayer(replayDir)
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			_, _, err := ChainDiscoveryWithOptions(client, data.TxHash, data.Options,
+				data.OutputIndex...)
+			if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != data.Code {
+				t.Fatalf("expected a %v error but found %v", data.Code, err)
+			}
+		})
+	}
+}
+
+// TestChainDiscoveryScripts tests that the chain discovery keeps the full
+// script metadata of multisig and stake outputs and ends the chain at the
+// nulldata outputs.
+func TestChainDiscoveryScripts(t *testing.T) {
+	const (
+		txHash     = "1111111111111111111111111111111111111111111111111111111111111111"
+		fundTxHash = "2222222222222222222222222222222222222222222222222222222222222222"
+	)
+
+	client := mapSource{
+		txHash: {
+			Txid: txHash,
+			Vin:  []dcrjson.Vin{{Txid: fundTxHash, Vout: 0, AmountIn: 2}},
+			Vout: []dcrjson.Vout{
+				{Value: 1.99, N: 0, ScriptPubKey: dcrjson.ScriptPubKeyResult{
+					Type: "multisig", ReqSigs: 2, Addresses: []string{"TsA", "TsB", "TsC"}}},
+				{Value: 0, N: 1, ScriptPubKey: dcrjson.ScriptPubKeyResult{
+					Type: "nulldata", Hex: "6a0401020304"}},
+			},
+		},
+		fundTxHash: {
+			Txid: fundTxHash,
+			Vin:  []dcrjson.Vin{{Stakebase: "00", AmountIn: 2}},
+			Vout: []dcrjson.Vout{
+				{Value: 2, N: 0, ScriptPubKey: dcrjson.ScriptPubKeyResult{
+					Type: "stakegen", ReqSigs: 1, Addresses: []string{"TsD"},
+					Hex: "bb76a914000000000000000000000000000000000000000088ac"}},
+			},
+		},
+	}
+
+	chain, _, err := ChainDiscoveryWithOptions(client, txHash, ChainOptions{MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("expected no error to be returned but found %v", err)
+	}
+
+	if len(chain) != 2 {
+		t.Fatalf("expected 2 chains but found %d", len(chain))
+	}
+
+	multisig := chain[0]
+	if multisig.ScriptType != "multisig" || multisig.ReqSigs != 2 ||
+		!reflect.DeepEqual(multisig.Addresses, []string{"TsA", "TsB", "TsC"}) {
+		t.Fatalf("expected the multisig script data but found %+v", multisig)
+	}
+
+	if len(multisig.Matched) != 1 || len(multisig.Matched[0].Inputs) != 1 {
+		t.Fatalf("expected a single funding input but found %+v", multisig.Matched)
+	}
+
+	stake := multisig.Matched[0].Inputs[0]
+	if stake.ScriptType != "stakegen" || stake.StakeSubclass != "pubkeyhash" ||
+		!reflect.DeepEqual(stake.Addresses, []string{"TsD"}) {
+		t.Fatalf("expected the stakegen script data but found %+v", stake)
+	}
+
+	nulldata := chain[1]
+	if nulldata.ScriptType != "nulldata" || len(nulldata.Addresses) != 0 ||
+		len(nulldata.Matched) != 0 || nulldata.StatusMsg == "" {
+		t.Fatalf("expected a terminal nulldata hub but found %+v", nulldata)
+	}
+}
+
+// TestChainDiscoveryDeterministicDepth tests that following only the
+// deterministic links stops at DefaultTraceDepth if no max depth is set.
+func TestChainDiscoveryDeterministicDepth(t *testing.T) {
+	hash := func(i int) string {
+		return fmt.Sprintf("%064x", i+1)
+	}
+
+	// Each tx spends the single output of the next one.
+	client := mapSource{}
+	for i := 0; i < 2*DefaultTraceDepth; i++ {
+		client[hash(i)] = &dcrjson.TxRawResult{
+			Txid: hash(i),
+			Vin:  []dcrjson.Vin{{Txid: hash(i + 1), Vout: 0, AmountIn: 1}},
+			Vout: []dcrjson.Vout{{Value: 1, N: 0}},
+		}
+	}
+
+	chain, _, err := ChainDiscoveryWithOptions(client, hash(0),
+		ChainOptions{DeterministicOnly: true})
+	if err != nil {
+		t.Fatalf("expected no error to be returned but found %v", err)
+	}
+
+	// The hubs at the max depth list their matched inputs.
+	if depth, _ := hubStats(chain[0]); depth != DefaultTraceDepth+1 {
+		t.Fatalf("expected the chain to be %d hubs deep but found %d",
+			DefaultTraceDepth+1, depth)
+	}
+}
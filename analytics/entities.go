@@ -0,0 +1,95 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// Labeler returns the entity labels of the labeled addresses. *labels.Store
+// satisfies this interface.
+type Labeler interface {
+	Lookup(addresses []string) []*labels.Label
+}
+
+// LabeledIO is a tx input or output paying to a labeled address. Index is the
+// input or the output index in the tx.
+type LabeledIO struct {
+	Index     int
+	Amount    float64
+	Addresses []string
+	Labels    []*labels.Label
+}
+
+// TxLabels lists the labeled inputs and outputs of a tx.
+type TxLabels struct {
+	Inputs  []*LabeledIO `json:",omitempty"`
+	Outputs []*LabeledIO `json:",omitempty"`
+}
+
+// TxEntityLabels returns the tx inputs and outputs paying to labeled
+// addresses. The inputs addresses are read from the outputs they spend thus
+// the inputs whose previous tx is not found are skipped. nil is returned if
+// neither the inputs nor the outputs are labeled.
+func TxEntityLabels(client rpcutils.TxSource, tx *rpcutils.Transaction,
+	labeler Labeler) (*TxLabels, error) {
+	txLabels := new(TxLabels)
+
+	// prevTxs caches the previous txs spent by more than one input.
+	prevTxs := make(map[string]*rpcutils.Transaction)
+
+	for i, in := range tx.Inpoints {
+		prevTx, ok := prevTxs[in.TxHash]
+		if !ok {
+			var err error
+			prevTx, err = RetrieveTxData(client, in.TxHash)
+			if code, isTyped := rpcutils.ErrorCodeOf(err); isTyped && code == rpcutils.ErrTxNotFound {
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+			prevTxs[in.TxHash] = prevTx
+		}
+
+		for _, out := range prevTx.Outpoints {
+			if out.TxIndex == in.OutputTxIndex {
+				if l := labeledIO(i, in.ValueIn, out.PkScriptData, labeler); l != nil {
+					txLabels.Inputs = append(txLabels.Inputs, l)
+				}
+				break
+			}
+		}
+	}
+
+	for i, out := range tx.Outpoints {
+		if l := labeledIO(i, out.Value, out.PkScriptData, labeler); l != nil {
+			txLabels.Outputs = append(txLabels.Outputs, l)
+		}
+	}
+
+	if len(txLabels.Inputs) == 0 && len(txLabels.Outputs) == 0 {
+		return nil, nil
+	}
+	return txLabels, nil
+}
+
+// labeledIO returns the labeled input or output. nil is returned if the script
+// does not pay to a labeled address.
+func labeledIO(index int, amount float64, script rpcutils.ScriptPubKeyData,
+	labeler Labeler) *LabeledIO {
+	l := labeler.Lookup(script.Addresses)
+	if len(l) == 0 {
+		return nil
+	}
+
+	return &LabeledIO{
+		Index:     index,
+		Amount:    amount,
+		Addresses: script.Addresses,
+		Labels:    l,
+	}
+}
@@ -0,0 +1,141 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
+)
+
+const (
+	labeledTxHash   = "3333333333333333333333333333333333333333333333333333333333333333"
+	exchangeTxHash  = "4444444444444444444444444444444444444444444444444444444444444444"
+	depositTxHash   = "5555555555555555555555555555555555555555555555555555555555555555"
+	missingPrevHash = "6666666666666666666666666666666666666666666666666666666666666666"
+)
+
+// mapLabeler is a Labeler that serves the labels from a map.
+type mapLabeler map[string]*labels.Label
+
+func (m mapLabeler) Lookup(addresses []string) []*labels.Label {
+	var l []*labels.Label
+	for _, addr := range addresses {
+		if label, ok := m[addr]; ok {
+			l = append(l, label)
+		}
+	}
+	return l
+}
+
+var (
+	exchangeLabel = &labels.Label{Address: "TsExchange", Entity: "Exchange A",
+		Category: labels.CategoryExchange}
+	poolLabel = &labels.Label{Address: "TsPool", Entity: "Pool B",
+		Category: labels.CategoryPool}
+
+	testLabeler = mapLabeler{"TsExchange": exchangeLabel, "TsPool": poolLabel}
+)
+
+// labeledSource returns a tx spending an exchange output that was funded by a
+// deposit to the exchange mined by a coinbase.
+func labeledSource() mapSource {
+	payTo := func(value float64, n uint32, addr string) dcrjson.Vout {
+		return dcrjson.Vout{Value: value, N: n, ScriptPubKey: dcrjson.ScriptPubKeyResult{
+			Type: "pubkeyhash", ReqSigs: 1, Addresses: []string{addr}}}
+	}
+
+	return mapSource{
+		labeledTxHash: {
+			Txid: labeledTxHash,
+			Vin:  []dcrjson.Vin{{Txid: exchangeTxHash, Vout: 0, AmountIn: 3}},
+			Vout: []dcrjson.Vout{payTo(1, 0, "TsUser"), payTo(1.99, 1, "TsPool")},
+		},
+		exchangeTxHash: {
+			Txid: exchangeTxHash,
+			Vin:  []dcrjson.Vin{{Txid: depositTxHash, Vout: 0, AmountIn: 3.01}},
+			Vout: []dcrjson.Vout{payTo(3, 0, "TsExchange")},
+		},
+		depositTxHash: {
+			Txid: depositTxHash,
+			Vin:  []dcrjson.Vin{{Coinbase: "00", AmountIn: 3.02}},
+			Vout: []dcrjson.Vout{payTo(3.01, 0, "TsDeposit")},
+		},
+	}
+}
+
+// TestTxEntityLabels tests that the tx inputs and outputs paying to labeled
+// addresses are listed.
+func TestTxEntityLabels(t *testing.T) {
+	client := labeledSource()
+
+	tx, err := RetrieveTxData(client, labeledTxHash)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	// An input whose previous tx is not found is skipped.
+	missing := tx.Inpoints[0]
+	missing.TxHash = missingPrevHash
+	tx.Inpoints = append(tx.Inpoints, missing)
+
+	txLabels, err := TxEntityLabels(client, tx, testLabeler)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	expected := &TxLabels{
+		Inputs: []*LabeledIO{{Index: 0, Amount: 3, Addresses: []string{"TsExchange"},
+			Labels: []*labels.Label{exchangeLabel}}},
+		Outputs: []*LabeledIO{{Index: 1, Amount: 1.99, Addresses: []string{"TsPool"},
+			Labels: []*labels.Label{poolLabel}}},
+	}
+
+	if !reflect.DeepEqual(txLabels, expected) {
+		t.Fatalf("expected labels %+v but found %+v", expected, txLabels)
+	}
+
+	txLabels, err = TxEntityLabels(client, tx, mapLabeler{})
+	if err != nil || txLabels != nil {
+		t.Fatalf("expected no labels and no error but found %+v and %v", txLabels, err)
+	}
+}
+
+// TestChainDiscoveryLabels tests that the chain hubs are labeled and that the
+// chain discovery stops at the labeled hubs if requested.
+func TestChainDiscoveryLabels(t *testing.T) {
+	type testData struct {
+		StopAtLabels bool
+		Expanded     bool
+	}
+
+	td := []testData{
+		{StopAtLabels: false, Expanded: true},
+		{StopAtLabels: true, Expanded: false},
+	}
+
+	for _, data := range td {
+		// The certain links are only followed past the root if the
+		// deterministic links are followed.
+		opts := ChainOptions{MaxDepth: 2, DeterministicOnly: true, Labels: testLabeler,
+			StopAtLabels: data.StopAtLabels}
+		chain, _, err := ChainDiscoveryWithOptions(labeledSource(), labeledTxHash, opts, 0)
+		if err != nil {
+			t.Fatalf("expected no error to be returned but found %v", err)
+		}
+
+		if len(chain) != 1 || len(chain[0].Matched) != 1 || len(chain[0].Matched[0].Inputs) != 1 {
+			t.Fatalf("expected a single funding input but found %+v", chain)
+		}
+
+		exchange := chain[0].Matched[0].Inputs[0]
+		if !reflect.DeepEqual(exchange.Labels, []*labels.Label{exchangeLabel}) {
+			t.Fatalf("expected the exchange label but found %+v", exchange.Labels)
+		}
+
+		if expanded := len(exchange.Matched) > 0; expanded != data.Expanded {
+			t.Fatalf("expected the labeled hub expansion to be %v but found %v: %+v",
+				data.Expanded, expanded, exchange)
+		}
+	}
+}
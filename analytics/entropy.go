@@ -0,0 +1,95 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"math"
+)
+
+// TxEntropy measures how private the tx funds flow is. The valid funds flow
+// interpretations are assumed to be equally likely.
+type TxEntropy struct {
+	// Interpretations is the number of distinct valid funds flow solutions.
+	Interpretations int
+
+	// Entropy is the Shannon entropy of the interpretations in bits.
+	Entropy float64
+
+	// Density is the entropy per input and output of the tx. It allows txs of
+	// different sizes to be compared.
+	Density float64
+
+	// Deterministic is true if every input either certainly funds or certainly
+	// does not fund each output.
+	Deterministic bool
+}
+
+// EntropyStats aggregates the entropy metrics of a batch of txs.
+type EntropyStats struct {
+	Txs           int
+	Deterministic int
+	MeanEntropy   float64
+	MaxEntropy    float64
+	MeanDensity   float64
+}
+
+// NewTxEntropy computes the tx entropy metrics from its link matrix. It
+// returns nil if the tx could not be analyzed.
+func NewTxEntropy(m *LinkMatrix) *TxEntropy {
+	if m == nil || m.Solutions == 0 {
+		return nil
+	}
+
+	entropy := math.Log2(float64(m.Solutions))
+
+	metrics := &TxEntropy{
+		Interpretations: m.Solutions,
+		Entropy:         roundOff(entropy),
+		Deterministic:   true,
+	}
+
+	if size := len(m.Inputs) + len(m.Outputs); size > 0 {
+		metrics.Density = roundOff(entropy / float64(size))
+	}
+
+	for _, row := range m.Probabilities {
+		for _, p := range row {
+			if p != 0 && p != 1 {
+				metrics.Deterministic = false
+			}
+		}
+	}
+
+	return metrics
+}
+
+// AggregateEntropy returns the entropy metrics summary of the analyses. The
+// analyses without entropy metrics are skipped.
+func AggregateEntropy(analyses []*TxAnalysis) *EntropyStats {
+	stats := new(EntropyStats)
+
+	var totalEntropy, totalDensity float64
+	for _, analysis := range analyses {
+		e := analysis.Entropy
+		if e == nil {
+			continue
+		}
+
+		stats.Txs++
+		if e.Deterministic {
+			stats.Deterministic++
+		}
+
+		totalEntropy += e.Entropy
+		totalDensity += e.Density
+		stats.MaxEntropy = math.Max(stats.MaxEntropy, e.Entropy)
+	}
+
+	if stats.Txs > 0 {
+		stats.MeanEntropy = roundOff(totalEntropy / float64(stats.Txs))
+		stats.MeanDensity = roundOff(totalDensity / float64(stats.Txs))
+	}
+
+	return stats
+}
@@ -0,0 +1,82 @@
+package analytics
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// TestNewTxEntropy tests the entropy metrics computed from the link matrix.
+func TestNewTxEntropy(t *testing.T) {
+	type testData struct {
+		Matrix   *LinkMatrix
+		Expected *TxEntropy
+	}
+
+	td := []testData{
+		{
+			Matrix: &LinkMatrix{
+				Inputs:        []float64{1, 2},
+				Outputs:       []float64{0.9, 1.9},
+				Probabilities: [][]float64{{1, 0}, {0, 1}},
+				Solutions:     1,
+			},
+			Expected: &TxEntropy{Interpretations: 1, Deterministic: true},
+		},
+		{
+			Matrix: &LinkMatrix{
+				Inputs:        []float64{1, 2, 3},
+				Outputs:       []float64{0.9, 1.9, 3},
+				Probabilities: [][]float64{{1, 0.5, 0}, {0.5, 1, 0}, {0, 0, 1}},
+				Solutions:     2,
+			},
+			Expected: &TxEntropy{Interpretations: 2, Entropy: 1, Density: 0.166666667},
+		},
+		{
+			Matrix: &LinkMatrix{
+				Inputs:        []float64{1, 1},
+				Outputs:       []float64{1, 1},
+				Probabilities: [][]float64{{0.5, 0.5}, {0.5, 0.5}},
+				Solutions:     1,
+			},
+			Expected: &TxEntropy{Interpretations: 1},
+		},
+		{
+			Matrix: &LinkMatrix{Solutions: 8, Inputs: []float64{1}, Outputs: []float64{1}},
+			Expected: &TxEntropy{Interpretations: 8, Entropy: 3, Density: 1.5,
+				Deterministic: true},
+		},
+		{Matrix: nil, Expected: nil},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			result := NewTxEntropy(data.Matrix)
+			if !reflect.DeepEqual(result, data.Expected) {
+				t.Fatalf("expected entropy metrics %+v but found %+v", data.Expected, result)
+			}
+		})
+	}
+}
+
+// TestAggregateEntropy tests the aggregation of the entropy metrics of a batch
+// of txs.
+func TestAggregateEntropy(t *testing.T) {
+	analyses := []*TxAnalysis{
+		{Entropy: &TxEntropy{Interpretations: 1, Deterministic: true}},
+		{Entropy: &TxEntropy{Interpretations: 4, Entropy: 2, Density: 0.5}},
+		{Entropy: &TxEntropy{Interpretations: 2, Entropy: 1, Density: 0.25}},
+		{},
+	}
+
+	expected := &EntropyStats{Txs: 3, Deterministic: 1, MeanEntropy: 1, MaxEntropy: 2,
+		MeanDensity: 0.25}
+
+	if result := AggregateEntropy(analyses); !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected entropy stats %+v but found %+v", expected, result)
+	}
+
+	if result := AggregateEntropy(nil); !reflect.DeepEqual(result, &EntropyStats{}) {
+		t.Fatalf("expected empty entropy stats but found %+v", result)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ExplainProbabilities turns the outputs funds flow probabilities into plain
+// language statements, one per output amount.
+func ExplainProbabilities(probabilities []*FlowProbability) []string {
+	statements := make([]string, 0, len(probabilities))
+
+	for _, p := range probabilities {
+		if p.StatusMsg != "" {
+			statements = append(statements, p.StatusMsg+".")
+			continue
+		}
+
+		output := "Output " + formatDCR(p.OutputAmount)
+		if p.Count > 1 {
+			output = fmt.Sprintf("Each of the %d outputs of %s", p.Count,
+				formatDCR(p.OutputAmount))
+		}
+
+		sets := p.ProbableInputs
+
+		switch {
+		case len(sets) == 0:
+			statements = append(statements, output+" has no identifiable funding inputs.")
+
+		case len(sets) == 1 && p.LinkingProbability == 1:
+			statements = append(statements, fmt.Sprintf(
+				"%s is deterministically funded by %s%s.", output, describeSet(sets[0]),
+				describeShare(sets[0])))
+
+		case len(sets) == 1:
+			statements = append(statements, fmt.Sprintf(
+				"%s is funded by %s with a linking probability of %s.", output,
+				describeSet(sets[0]), formatPercent(p.LinkingProbability)))
+
+		default:
+			largest := sets[0]
+			for _, set := range sets[1:] {
+				if set.PercentOfInputs > largest.PercentOfInputs {
+					largest = set
+				}
+			}
+
+			statements = append(statements, fmt.Sprintf(
+				"%s has %d possible funding sets; the set contributing the largest "+
+					"share is %s%s, with a linking probability of %s.",
+				output, len(sets), describeSet(largest), describeShare(largest),
+				formatPercent(setProbability(p, largest))))
+		}
+	}
+
+	return statements
+}
+
+// ExplainSolutions turns the raw funds flow solutions into plain language
+// statements: the total number of interpretations found followed by the
+// buckets of each solution. total may exceed the solutions count if they are
+// a page of the solutions.
+func ExplainSolutions(solutions []*AllFundsFlows, total int) []string {
+	if len(solutions) == 1 && solutions[0].StatusMsg != "" {
+		return []string{solutions[0].StatusMsg + "."}
+	}
+
+	statements := []string{fmt.Sprintf(
+		"The inputs and outputs amounts match in %d possible interpretation(s).",
+		total)}
+
+	if total == 1 && len(solutions) == 1 && solutions[0].Solution == 0 {
+		statements[0] = "No matching amounts were found between the inputs and the " +
+			"outputs; the unmatched inputs are treated as funding all the unmatched outputs."
+	}
+
+	for _, sol := range solutions {
+		buckets := make([]string, len(sol.FundsFlow))
+		for i, bucket := range sol.FundsFlow {
+			buckets[i] = fmt.Sprintf("%s fund %s (fee %s)",
+				formatAmounts(bucket.Inputs.Values), formatAmounts(bucket.MatchedOutputs.Values),
+				formatDCR(bucket.Fee))
+		}
+
+		statements = append(statements, fmt.Sprintf("Solution %d: %s.", sol.Solution,
+			strings.Join(buckets, "; ")))
+	}
+
+	return statements
+}
+
+// ExplainAnalysis turns the tx analysis into plain language statements: the
+// number of interpretations found followed by one statement per output amount.
+func ExplainAnalysis(analysis *TxAnalysis) []string {
+	solutions := ExplainSolutions(analysis.Solutions, len(analysis.Solutions))
+	if len(analysis.Solutions) == 1 && analysis.Solutions[0].StatusMsg != "" {
+		return solutions
+	}
+
+	return append(solutions[:1], ExplainProbabilities(analysis.Probabilities)...)
+}
+
+// describeSet returns the plain language description of the input set.
+func describeSet(set *InputSets) string {
+	if len(set.Set) == 1 {
+		d := set.Set[0]
+		if d.PossibleInputs > 1 {
+			return fmt.Sprintf("one of the %d inputs of %s", d.PossibleInputs,
+				formatDCR(d.Amount))
+		}
+		return "input " + formatDCR(d.Amount)
+	}
+
+	amounts := make([]float64, len(set.Set))
+	for i, d := range set.Set {
+		amounts[i] = d.Amount
+	}
+	return "inputs " + formatAmounts(amounts) + " together"
+}
+
+// setProbability returns the probability of the output being funded by the
+// input set. A set of one of several inputs of the same amount is as probable
+// as all those inputs together.
+func setProbability(p *FlowProbability, set *InputSets) float64 {
+	if len(set.Set) == 1 && set.Set[0].PossibleInputs > 1 {
+		return math.Min(1, p.LinkingProbability*float64(set.Set[0].PossibleInputs))
+	}
+	return p.LinkingProbability
+}
+
+// describeShare returns the share of the inputs value received if the output
+// does not receive the whole value.
+func describeShare(set *InputSets) string {
+	if set.PercentOfInputs >= 1 || set.PercentOfInputs <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(", receiving %s of the funding inputs value",
+		formatPercent(set.PercentOfInputs))
+}
+
+// formatDCR formats the amount in DCR.
+func formatDCR(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64) + " DCR"
+}
+
+// formatAmounts formats the list of amounts in DCR.
+func formatAmounts(amounts []float64) string {
+	if len(amounts) == 1 {
+		return formatDCR(amounts[0])
+	}
+
+	values := make([]string, len(amounts))
+	for i, amount := range amounts {
+		values[i] = strconv.FormatFloat(amount, 'f', -1, 64)
+	}
+	return "[" + strings.Join(values, ", ") + "] DCR"
+}
+
+// formatPercent formats the fraction as a percentage with up to two decimal
+// places.
+func formatPercent(fraction float64) string {
+	return strconv.FormatFloat(math.Round(fraction*1e4)/100, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,129 @@
+package analytics
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// TestExplainProbabilities tests the plain language statements generated from
+// the outputs funds flow probabilities.
+func TestExplainProbabilities(t *testing.T) {
+	type testData struct {
+		Probability *FlowProbability
+		Expected    []string
+	}
+
+	td := []testData{
+		{
+			Probability: &FlowProbability{OutputAmount: 2.5, Count: 1, LinkingProbability: 1,
+				ProbableInputs: []*InputSets{{Set: []*Details{{Amount: 2.51}}, PercentOfInputs: 1}}},
+			Expected: []string{"Output 2.5 DCR is deterministically funded by input 2.51 DCR."},
+		},
+		{
+			Probability: &FlowProbability{OutputAmount: 1, Count: 2, LinkingProbability: 1,
+				ProbableInputs: []*InputSets{{Set: []*Details{{Amount: 3}}, PercentOfInputs: 0.25}}},
+			Expected: []string{"Each of the 2 outputs of 1 DCR is deterministically funded " +
+				"by input 3 DCR, receiving 25% of the funding inputs value."},
+		},
+		{
+			Probability: &FlowProbability{OutputAmount: 1, Count: 1, LinkingProbability: 0.5,
+				ProbableInputs: []*InputSets{{Set: []*Details{{Amount: 1.1, PossibleInputs: 2}},
+					PercentOfInputs: 1}}},
+			Expected: []string{"Output 1 DCR is funded by one of the 2 inputs of 1.1 DCR " +
+				"with a linking probability of 50%."},
+		},
+		{
+			Probability: &FlowProbability{OutputAmount: 1, Count: 1, LinkingProbability: 1.0 / 3,
+				ProbableInputs: []*InputSets{
+					{Set: []*Details{{Amount: 1.1}}, PercentOfInputs: 1},
+					{Set: []*Details{{Amount: 0.5}, {Amount: 1}}, PercentOfInputs: 0.4},
+					{Set: []*Details{{Amount: 2}}, PercentOfInputs: 0.5},
+				}},
+			Expected: []string{"Output 1 DCR has 3 possible funding sets; the set " +
+				"contributing the largest share is input 1.1 DCR, with a linking " +
+				"probability of 33.33%."},
+		},
+		{
+			Probability: &FlowProbability{OutputAmount: 1, Count: 1, LinkingProbability: 0.25,
+				ProbableInputs: []*InputSets{
+					{Set: []*Details{{Amount: 1.1, PossibleInputs: 3}}, PercentOfInputs: 1},
+					{Set: []*Details{{Amount: 0.5}, {Amount: 1}}, PercentOfInputs: 0.4},
+				}},
+			Expected: []string{"Output 1 DCR has 2 possible funding sets; the set " +
+				"contributing the largest share is one of the 3 inputs of 1.1 DCR, with a " +
+				"linking probability of 75%."},
+		},
+		{
+			Probability: &FlowProbability{OutputAmount: 1, Count: 1},
+			Expected:    []string{"Output 1 DCR has no identifiable funding inputs."},
+		},
+		{
+			Probability: &FlowProbability{StatusMsg: "Output amount not found"},
+			Expected:    []string{"Output amount not found."},
+		},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			result := ExplainProbabilities([]*FlowProbability{data.Probability})
+			if !reflect.DeepEqual(result, data.Expected) {
+				t.Fatalf("expected statements %q but found %q", data.Expected, result)
+			}
+		})
+	}
+}
+
+// TestExplainSolutions tests the plain language statements generated from the
+// raw funds flow solutions.
+func TestExplainSolutions(t *testing.T) {
+	type testData struct {
+		Solutions []*AllFundsFlows
+		Total     int
+		Expected  []string
+	}
+
+	td := []testData{
+		{
+			Solutions: []*AllFundsFlows{{Solution: 1, FundsFlow: []TxFundsFlow{
+				{Fee: 0.01, Inputs: GroupedValues{Values: []float64{2.51}},
+					MatchedOutputs: GroupedValues{Values: []float64{2.5}}},
+				{Fee: 0, Inputs: GroupedValues{Values: []float64{1, 2}},
+					MatchedOutputs: GroupedValues{Values: []float64{3}}},
+			}}},
+			Total: 4,
+			Expected: []string{
+				"The inputs and outputs amounts match in 4 possible interpretation(s).",
+				"Solution 1: 2.51 DCR fund 2.5 DCR (fee 0.01 DCR); [1, 2] DCR fund 3 DCR (fee 0 DCR).",
+			},
+		},
+		{
+			Solutions: []*AllFundsFlows{{Solution: 0, FundsFlow: []TxFundsFlow{
+				{Fee: 0.1, Inputs: GroupedValues{Values: []float64{5}},
+					MatchedOutputs: GroupedValues{Values: []float64{2, 2.9}}},
+				{Fee: 0, Inputs: GroupedValues{Values: []float64{1}},
+					MatchedOutputs: GroupedValues{Values: []float64{1}}},
+			}}},
+			Total: 1,
+			Expected: []string{
+				"No matching amounts were found between the inputs and the outputs; " +
+					"the unmatched inputs are treated as funding all the unmatched outputs.",
+				"Solution 0: 5 DCR fund [2, 2.9] DCR (fee 0.1 DCR); 1 DCR fund 1 DCR (fee 0 DCR).",
+			},
+		},
+		{
+			Solutions: []*AllFundsFlows{{StatusMsg: "Too many inputs"}},
+			Total:     1,
+			Expected:  []string{"Too many inputs."},
+		},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			result := ExplainSolutions(data.Solutions, data.Total)
+			if !reflect.DeepEqual(result, data.Expected) {
+				t.Fatalf("expected statements %q but found %q", data.Expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// FeeMode defines how the tx fee is expected to be split between the funds
+// flow buckets of a solution.
+type FeeMode string
+
+const (
+	// FeeModeAny allows each bucket to pay any part of the tx fee as long as
+	// the buckets fees add up to the tx fee.
+	FeeModeAny FeeMode = "any"
+
+	// FeeModeSingle expects the whole tx fee to be paid by a single bucket.
+	FeeModeSingle FeeMode = "single"
+
+	// FeeModeProportional expects each bucket to pay a share of the tx fee
+	// proportional to its number of inputs since the fee is paid according to
+	// the tx size.
+	FeeModeProportional FeeMode = "proportional"
+)
+
+// FeeModel defines the tolerance of the buckets fees matching. Tolerance is
+// the fraction of the tx fee a bucket fee may deviate from its proportional
+// share of the tx fee in the FeeModeProportional mode.
+type FeeModel struct {
+	Mode      FeeMode
+	Tolerance float64
+}
+
+// TxFee holds the tx fee details used by the fee model. Size is the serialized
+// tx size in bytes and FeeRate the fee rate in DCR/kB.
+type TxFee struct {
+	Fee     float64
+	Size    int     `json:",omitempty"`
+	FeeRate float64 `json:",omitempty"`
+	Mode    FeeMode
+}
+
+// feeModel is the fee model used by all the funds flow analyses.
+var feeModel = FeeModel{Mode: FeeModeAny}
+
+// SetFeeModel sets the fee model used by all the funds flow analyses. It
+// should be called before any analysis is run. An ErrInvalidRequest error is
+// returned if the fee model is invalid.
+func SetFeeModel(m FeeModel) error {
+	switch m.Mode {
+	case FeeModeAny, FeeModeSingle, FeeModeProportional:
+	default:
+		return rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			fmt.Sprintf("invalid fee mode %q: expected %s, %s or %s", m.Mode,
+				FeeModeAny, FeeModeSingle, FeeModeProportional))
+	}
+
+	if m.Tolerance < 0 || m.Tolerance > 1 {
+		return rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			fmt.Sprintf("invalid fee tolerance %v: expected a value between 0 and 1",
+				m.Tolerance))
+	}
+
+	feeModel = m
+	return nil
+}
+
+// newTxFee returns the fee details of the tx.
+func newTxFee(tx *rpcutils.Transaction) *TxFee {
+	return &TxFee{
+		Fee:     tx.Fees,
+		Size:    tx.Size,
+		FeeRate: tx.FeeRate(),
+		Mode:    feeModel.Mode,
+	}
+}
+
+// allows checks if the bucket fee is acceptable for a tx with the fee and the
+// number of inputs provided.
+func (m FeeModel) allows(bucket TxFundsFlow, txFee float64, inputsCount int) bool {
+	fee := roundOff(bucket.Fee)
+	if fee < 0 || fee > roundOff(txFee) {
+		return false
+	}
+
+	switch m.Mode {
+	case FeeModeSingle:
+		return fee == 0 || fee == roundOff(txFee)
+
+	case FeeModeProportional:
+		if inputsCount == 0 {
+			return false
+		}
+
+		share := txFee * float64(len(bucket.Inputs.Values)) / float64(inputsCount)
+		return math.Abs(fee-share) <= roundOff(m.Tolerance*txFee)
+	}
+
+	return true
+}
+
+// filterBuckets returns the buckets whose fees are acceptable.
+func (m FeeModel) filterBuckets(buckets []TxFundsFlow, txFee float64,
+	inputsCount int) []TxFundsFlow {
+	if m.Mode == FeeModeAny {
+		return buckets
+	}
+
+	filtered := buckets[:0]
+	for _, bucket := range buckets {
+		if m.allows(bucket, txFee, inputsCount) {
+			filtered = append(filtered, bucket)
+		}
+	}
+	return filtered
+}
+
+// filterSolutions returns the solutions whose buckets fees are all acceptable.
+func (m FeeModel) filterSolutions(solutions []*AllFundsFlows, txFee float64,
+	inputsCount int) []*AllFundsFlows {
+	if m.Mode == FeeModeAny {
+		return solutions
+	}
+
+	var filtered []*AllFundsFlows
+solutionsLoop:
+	for _, sol := range solutions {
+		for _, bucket := range sol.FundsFlow {
+			if !m.allows(bucket, txFee, inputsCount) {
+				continue solutionsLoop
+			}
+		}
+		filtered = append(filtered, sol)
+	}
+	return filtered
+}
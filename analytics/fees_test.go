@@ -0,0 +1,110 @@
+package analytics
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// TestFeeModel tests the funds flow solutions retained by each fee model.
+// https://testnet.dcrdata.org/tx/ae40333aed99c0b004ef01834444944c471bc72ce869949f6f8ced728941f561
+func TestFeeModel(t *testing.T) {
+	txTestData := &rpcutils.Transaction{
+		Fees:        0.000672,
+		NumInpoint:  3,
+		NumOutpoint: 4,
+		Inpoints: []rpcutils.TxInput{
+			{ValueIn: 39.96949337},
+			{ValueIn: 40.9873785},
+			{ValueIn: 5076.66042217},
+		},
+		Outpoints: []rpcutils.TxOutput{
+			{Value: 39.96907437},
+			{Value: 40.9873785},
+			{Value: 40.9873785},
+			{Value: 5035.67279067},
+		},
+	}
+
+	type testData struct {
+		Model             FeeModel
+		ExpectedSolutions []int
+	}
+
+	td := []testData{
+		{Model: FeeModel{Mode: FeeModeAny}, ExpectedSolutions: []int{1}},
+		{Model: FeeModel{Mode: FeeModeSingle}, ExpectedSolutions: []int{0}},
+		{Model: FeeModel{Mode: FeeModeProportional, Tolerance: 0.1}, ExpectedSolutions: []int{0}},
+		{Model: FeeModel{Mode: FeeModeProportional, Tolerance: 1}, ExpectedSolutions: []int{1}},
+	}
+
+	defer func(m FeeModel) { feeModel = m }(feeModel)
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			if err := SetFeeModel(data.Model); err != nil {
+				t.Fatalf("expected no error but found: %v", err)
+			}
+
+			result, _, _, err := TransactionFundsFlow(txTestData)
+			if err != nil {
+				t.Fatalf("expected no error but found: %v", err)
+			}
+
+			var solutions []int
+			for _, sol := range result {
+				solutions = append(solutions, sol.Solution)
+			}
+
+			if !reflect.DeepEqual(solutions, data.ExpectedSolutions) {
+				t.Fatalf("expected solutions %v but found %v", data.ExpectedSolutions, solutions)
+			}
+		})
+	}
+}
+
+// TestSetFeeModel tests the validation of the fee model settings.
+func TestSetFeeModel(t *testing.T) {
+	type testData struct {
+		Model       FeeModel
+		ExpectedErr bool
+	}
+
+	td := []testData{
+		{Model: FeeModel{Mode: FeeModeProportional, Tolerance: 0.5}},
+		{Model: FeeModel{Mode: "greedy"}, ExpectedErr: true},
+		{Model: FeeModel{Mode: FeeModeAny, Tolerance: -0.1}, ExpectedErr: true},
+		{Model: FeeModel{Mode: FeeModeSingle, Tolerance: 1.5}, ExpectedErr: true},
+	}
+
+	defer func(m FeeModel) { feeModel = m }(feeModel)
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			err := SetFeeModel(data.Model)
+			if !data.ExpectedErr {
+				if err != nil {
+					t.Fatalf("expected no error but found: %v", err)
+				}
+				return
+			}
+
+			code, ok := rpcutils.ErrorCodeOf(err)
+			if !ok || code != rpcutils.ErrInvalidRequest {
+				t.Fatalf("expected error code %v but found %v", rpcutils.ErrInvalidRequest, err)
+			}
+		})
+	}
+}
+
+// TestNewTxFee tests the tx fee details returned for a tx.
+func TestNewTxFee(t *testing.T) {
+	tx := &rpcutils.Transaction{Fees: 0.000672, Size: 448}
+	expected := &TxFee{Fee: 0.000672, Size: 448, FeeRate: 0.0015, Mode: feeModel.Mode}
+
+	if result := newTxFee(tx); !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected tx fee %+v but found %+v", expected, result)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"sort"
+)
+
+// LinkMatrix holds the probability of each tx input funding each tx output.
+// Inputs and Outputs list the tx amounts in ascending order and Probabilities
+// has a row per input and a column per output. An entry is the fraction of
+// the valid funds flow solutions in which the input funds the output. Inputs
+// or outputs with the same amount cannot be told apart and thus share their
+// links equally.
+type LinkMatrix struct {
+	Inputs        []float64
+	Outputs       []float64
+	Probabilities [][]float64
+	Solutions     int
+}
+
+// DeterministicLink is an input to output link found in every valid funds
+// flow solution of a tx. Inputs or outputs with the same amount cannot be told
+// apart thus the link holds for one of the inputs and one of the outputs with
+// the amounts. Prefabricated is set if the input and the output amounts are
+// equal and were matched as a zero fee bucket before the solutions search.
+type DeterministicLink struct {
+	InputAmount   float64
+	OutputAmount  float64
+	Prefabricated bool `json:",omitempty"`
+}
+
+// link identifies an input and an output amounts pair.
+type link struct {
+	in, out float64
+}
+
+// TxDeterministicLinks returns the input to output links found in all the tx
+// funds flow solutions sorted by the input and the output amounts. It returns
+// nil if the tx could not be analyzed.
+func TxDeterministicLinks(rawData []*AllFundsFlows) []*DeterministicLink {
+	if len(rawData) == 0 || (len(rawData) == 1 && rawData[0].StatusMsg != "") {
+		return nil
+	}
+
+	// counts holds the number of solutions in which each link is found.
+	counts := make(map[link]int)
+	prefabricated := make(map[link]bool)
+
+	for _, sol := range rawData {
+		found := make(map[link]bool)
+		for _, bucket := range sol.FundsFlow {
+			ins, outs := bucket.Inputs.Values, bucket.MatchedOutputs.Values
+			for _, in := range ins {
+				for _, out := range outs {
+					// Nulldata outputs do not hold any amount to be funded.
+					if out <= 0 {
+						continue
+					}
+					found[link{in, out}] = true
+				}
+			}
+
+			if bucket.Fee == 0 && len(ins) == 1 && len(outs) == 1 && ins[0] == outs[0] {
+				prefabricated[link{ins[0], outs[0]}] = true
+			}
+		}
+
+		for l := range found {
+			counts[l]++
+		}
+	}
+
+	var links []*DeterministicLink
+	for l, count := range counts {
+		if count == len(rawData) {
+			links = append(links, &DeterministicLink{
+				InputAmount:   l.in,
+				OutputAmount:  l.out,
+				Prefabricated: prefabricated[l],
+			})
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].InputAmount == links[j].InputAmount {
+			return links[i].OutputAmount < links[j].OutputAmount
+		}
+		return links[i].InputAmount < links[j].InputAmount
+	})
+
+	return links
+}
+
+// isDeterministic checks if the input amount funds the output amount in all
+// the tx funds flow solutions.
+func isDeterministic(links []*DeterministicLink, input, output float64) bool {
+	for _, l := range links {
+		if l.InputAmount == input && l.OutputAmount == output {
+			return true
+		}
+	}
+	return false
+}
+
+// TxLinkMatrix computes the link probability matrix of the tx inputs and
+// outputs from all its funds flow solutions. It returns nil if the tx could
+// not be analyzed.
+func TxLinkMatrix(rawData []*AllFundsFlows, inputs, outputs []float64) *LinkMatrix {
+	if len(rawData) == 0 || (len(rawData) == 1 && rawData[0].StatusMsg != "") {
+		return nil
+	}
+
+	m := &LinkMatrix{
+		Inputs:        sortedAmounts(inputs),
+		Outputs:       sortedAmounts(outputs),
+		Probabilities: make([][]float64, 0, len(inputs)),
+		Solutions:     len(rawData),
+	}
+
+	inputsCount := make(map[float64]float64)
+	for _, in := range m.Inputs {
+		inputsCount[in]++
+	}
+
+	outputsCount := make(map[float64]float64)
+	for _, out := range m.Outputs {
+		outputsCount[out]++
+	}
+
+	for range m.Inputs {
+		m.Probabilities = append(m.Probabilities, make([]float64, len(m.Outputs)))
+	}
+
+	for _, sol := range rawData {
+		for _, bucket := range sol.FundsFlow {
+			bucketIns := make(map[float64]float64)
+			for _, in := range bucket.Inputs.Values {
+				bucketIns[in]++
+			}
+
+			bucketOuts := make(map[float64]float64)
+			for _, out := range bucket.MatchedOutputs.Values {
+				bucketOuts[out]++
+			}
+
+			// An input funds an output in the solution if both are in the same
+			// bucket. Each of the identical amounts is equally likely to be the
+			// one in the bucket.
+			for i, in := range m.Inputs {
+				if bucketIns[in] == 0 {
+					continue
+				}
+
+				inShare := bucketIns[in] / inputsCount[in]
+				for j, out := range m.Outputs {
+					if bucketOuts[out] == 0 {
+						continue
+					}
+					m.Probabilities[i][j] += inShare * bucketOuts[out] / outputsCount[out]
+				}
+			}
+		}
+	}
+
+	for _, row := range m.Probabilities {
+		for j := range row {
+			row[j] = roundOff(row[j] / float64(len(rawData)))
+		}
+	}
+
+	return m
+}
+
+// Probability returns the probability of the input amount funding the output
+// amount. Zero is returned if either amount is not in the tx.
+func (m *LinkMatrix) Probability(input, output float64) float64 {
+	i := sort.SearchFloat64s(m.Inputs, input)
+	j := sort.SearchFloat64s(m.Outputs, output)
+	if i == len(m.Inputs) || m.Inputs[i] != input ||
+		j == len(m.Outputs) || m.Outputs[j] != output {
+		return 0
+	}
+	return m.Probabilities[i][j]
+}
+
+// sortedAmounts returns a sorted copy of the amounts without the doping
+// element.
+func sortedAmounts(amounts []float64) []float64 {
+	sorted := make([]float64, 0, len(amounts))
+	for _, amount := range amounts {
+		if amount != dopingElement {
+			sorted = append(sorted, amount)
+		}
+	}
+
+	sort.Float64s(sorted)
+	return sorted
+}
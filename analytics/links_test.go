@@ -0,0 +1,160 @@
+package analytics
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// TestTxLinkMatrix tests the computation of the link probability matrix from
+// the funds flow solutions.
+func TestTxLinkMatrix(t *testing.T) {
+	bucket := func(in, out []float64) TxFundsFlow {
+		return TxFundsFlow{
+			Inputs:         GroupedValues{Values: in},
+			MatchedOutputs: GroupedValues{Values: out},
+		}
+	}
+
+	type testData struct {
+		Solutions []*AllFundsFlows
+		Inputs    []float64
+		Outputs   []float64
+		Expected  *LinkMatrix
+	}
+
+	td := []testData{
+		{
+			Solutions: []*AllFundsFlows{
+				{Solution: 1, FundsFlow: []TxFundsFlow{
+					bucket([]float64{1, 2}, []float64{0.9, 1.9}),
+					bucket([]float64{3}, []float64{3}),
+				}},
+				{Solution: 2, FundsFlow: []TxFundsFlow{
+					bucket([]float64{1}, []float64{0.9}),
+					bucket([]float64{2}, []float64{1.9}),
+					bucket([]float64{3}, []float64{3}),
+				}},
+			},
+			Inputs:  []float64{3, 1, 2},
+			Outputs: []float64{1.9, 0.9, 3},
+			Expected: &LinkMatrix{
+				Inputs:  []float64{1, 2, 3},
+				Outputs: []float64{0.9, 1.9, 3},
+				Probabilities: [][]float64{
+					{1, 0.5, 0},
+					{0.5, 1, 0},
+					{0, 0, 1},
+				},
+				Solutions: 2,
+			},
+		},
+		{
+			// Identical amounts share their links equally.
+			Solutions: []*AllFundsFlows{
+				{Solution: 1, FundsFlow: []TxFundsFlow{
+					bucket([]float64{1}, []float64{1}),
+					bucket([]float64{1}, []float64{1}),
+				}},
+			},
+			Inputs:  []float64{1, 1, dopingElement},
+			Outputs: []float64{1, 1, dopingElement},
+			Expected: &LinkMatrix{
+				Inputs:        []float64{1, 1},
+				Outputs:       []float64{1, 1},
+				Probabilities: [][]float64{{0.5, 0.5}, {0.5, 0.5}},
+				Solutions:     1,
+			},
+		},
+		{
+			Solutions: []*AllFundsFlows{{StatusMsg: complexTxMsg}},
+			Inputs:    []float64{1},
+			Outputs:   []float64{1},
+		},
+		{},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			result := TxLinkMatrix(data.Solutions, data.Inputs, data.Outputs)
+			if !reflect.DeepEqual(result, data.Expected) {
+				t.Fatalf("expected link matrix %+v but found %+v", data.Expected, result)
+			}
+
+			if result == nil {
+				return
+			}
+
+			for k, in := range result.Inputs {
+				for j, out := range result.Outputs {
+					if p := result.Probability(in, out); p != result.Probabilities[k][j] {
+						t.Fatalf("expected the %v -> %v probability to be %v but found %v",
+							in, out, result.Probabilities[k][j], p)
+					}
+				}
+			}
+
+			if p := result.Probability(7, result.Outputs[0]); p != 0 {
+				t.Fatalf("expected an unknown input probability to be 0 but found %v", p)
+			}
+		})
+	}
+}
+
+// TestTxDeterministicLinks tests the detection of the links found in all the
+// funds flow solutions.
+func TestTxDeterministicLinks(t *testing.T) {
+	bucket := func(fee float64, in, out []float64) TxFundsFlow {
+		return TxFundsFlow{
+			Fee:            fee,
+			Inputs:         GroupedValues{Values: in},
+			MatchedOutputs: GroupedValues{Values: out},
+		}
+	}
+
+	type testData struct {
+		Solutions []*AllFundsFlows
+		Expected  []*DeterministicLink
+	}
+
+	td := []testData{
+		{
+			Solutions: []*AllFundsFlows{
+				{Solution: 1, FundsFlow: []TxFundsFlow{
+					bucket(0.2, []float64{1, 2}, []float64{0.9, 1.9}),
+					bucket(0, []float64{3}, []float64{3}),
+				}},
+				{Solution: 2, FundsFlow: []TxFundsFlow{
+					bucket(0.1, []float64{1}, []float64{0.9}),
+					bucket(0.1, []float64{2}, []float64{1.9}),
+					bucket(0, []float64{3}, []float64{3}),
+				}},
+			},
+			Expected: []*DeterministicLink{
+				{InputAmount: 1, OutputAmount: 0.9},
+				{InputAmount: 2, OutputAmount: 1.9},
+				{InputAmount: 3, OutputAmount: 3, Prefabricated: true},
+			},
+		},
+		{
+			// Nulldata outputs are not linked.
+			Solutions: []*AllFundsFlows{
+				{Solution: 0, FundsFlow: []TxFundsFlow{
+					bucket(0.1, []float64{5}, []float64{0, 4.9}),
+				}},
+			},
+			Expected: []*DeterministicLink{{InputAmount: 5, OutputAmount: 4.9}},
+		},
+		{Solutions: []*AllFundsFlows{{StatusMsg: complexTxMsg}}},
+		{},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			result := TxDeterministicLinks(data.Solutions)
+			if !reflect.DeepEqual(result, data.Expected) {
+				t.Fatalf("expected deterministic links %v but found %v", data.Expected, result)
+			}
+		})
+	}
+}
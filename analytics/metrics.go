@@ -0,0 +1,91 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Funds flow analysis phases timed by the phaseDuration histogram.
+const (
+	phasePrefabBuckets = "prefabricated_buckets"
+	phaseCombinations  = "combinations"
+	phaseTreeInsert    = "tree_insert"
+	phaseFindX         = "find_x"
+	phaseGetSolutions  = "get_solutions"
+)
+
+var (
+	// phaseDuration tracks how long each funds flow analysis phase takes.
+	phaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "dca",
+		Subsystem: "analysis",
+		Name:      "phase_duration_seconds",
+		Help:      "Duration of each funds flow analysis phase.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"phase"})
+
+	// complexTxs counts the txs rejected for being too complex to analyze.
+	complexTxs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "dca",
+		Subsystem: "analysis",
+		Name:      "complex_txs_total",
+		Help:      "Number of transactions rejected for being too complex to analyze.",
+	})
+
+	// solutionsCount tracks the number of solutions found per analyzed tx.
+	solutionsCount = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "dca",
+		Subsystem: "analysis",
+		Name:      "solutions",
+		Help:      "Number of funds flow solutions found per transaction.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	// chainDepth tracks the deepest level reached by each output chain.
+	chainDepth = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "dca",
+		Subsystem: "chain",
+		Name:      "depth",
+		Help:      "Deepest level reached by the chain discovery of an output.",
+		Buckets:   prometheus.LinearBuckets(1, 2, 10),
+	})
+
+	// chainHubs tracks the number of hubs found by each output chain.
+	chainHubs = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "dca",
+		Subsystem: "chain",
+		Name:      "hubs",
+		Help:      "Number of hubs found by the chain discovery of an output.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+)
+
+func init() {
+	prometheus.MustRegister(phaseDuration, complexTxs, solutionsCount,
+		chainDepth, chainHubs)
+}
+
+// observePhase records the time elapsed since t for the provided phase.
+func observePhase(phase string, t time.Time) {
+	phaseDuration.WithLabelValues(phase).Observe(time.Since(t).Seconds())
+}
+
+// hubStats returns the depth of the deepest path and the number of hubs in the
+// chain starting from the provided hub.
+func hubStats(h *Hub) (depth, hubs int) {
+	hubs = 1
+	for _, set := range h.Matched {
+		for _, input := range set.Inputs {
+			d, n := hubStats(input)
+			if d > depth {
+				depth = d
+			}
+			hubs += n
+		}
+	}
+	return depth + 1, hubs
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// DefaultPathDepth is the depth the most probable path search stops at if no
+// max depth is set.
+const DefaultPathDepth = 10
+
+// PathHop is an output on the most probable path. PathProbability is the
+// probability of the output funding the searched output and
+// PathPercentOfInputs the share of the output value passed on along the path.
+type PathHop struct {
+	TxHash              string
+	Vout                uint32
+	Amount              float64
+	Addresses           []string `json:",omitempty"`
+	PathProbability     float64
+	PathPercentOfInputs float64
+}
+
+// ProbablePath is the most probable path from the searched output, the first
+// hop, to an output of the source tx or paying to the source address, the last
+// hop. Expanded is the number of outputs whose funding inputs were analyzed.
+type ProbablePath struct {
+	Source              string
+	Hops                []*PathHop
+	Probability         float64
+	PathPercentOfInputs float64
+	Expanded            int
+}
+
+// pathNode is an output reached by the search. parent is the output it funds
+// on the path and seq the order it was reached in.
+type pathNode struct {
+	hub         *Hub
+	parent      *pathNode
+	depth       int
+	probability float64
+	poi         float64
+	seq         int
+}
+
+// pathQueue is a max heap of the reached outputs by their path probability
+// and by the share of the value passed on along the path. It satisfies the
+// heap.Interface.
+type pathQueue []*pathNode
+
+func (q pathQueue) Len() int {
+	return len(q)
+}
+
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].probability != q[j].probability {
+		return q[i].probability > q[j].probability
+	}
+
+	if q[i].poi != q[j].poi {
+		return q[i].poi > q[j].poi
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q pathQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *pathQueue) Push(x interface{}) {
+	*q = append(*q, x.(*pathNode))
+}
+
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// MostProbablePath returns the most probable path from the tx output at the
+// index to an output of the source tx or paying to the source address and the
+// tx block time. The source is taken as a tx hash if it is one. The outputs
+// are searched best first, the most probable path first, thus only the outputs
+// more probable than the path found have their funding inputs analyzed. The
+// output at the index is at depth 1 and, like the chains, the outputs at the
+// max depth of the options, or DefaultPathDepth if not set, are the last to
+// have their funding inputs analyzed. An ErrPathNotFound error is returned if
+// no path is found.
+func MostProbablePath(client rpcutils.TxSource, txHash string, index int, source string,
+	opts ChainOptions) (*ProbablePath, int64, error) {
+	if source == "" {
+		return nil, 0, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			"a source tx hash or address is required")
+	}
+
+	if opts.MaxDepth < 0 {
+		return nil, 0, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			"the path max depth should not be negative")
+	}
+
+	maxDepth := opts.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultPathDepth
+	}
+
+	tx, err := RetrieveTxData(client, txHash)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if index < 0 || index >= len(tx.Outpoints) {
+		return nil, tx.BlockTime, rpcutils.NewError(rpcutils.ErrInvalidOutputIndex,
+			fmt.Sprintf("transaction %s has no output at index %d", tx.TxID, index))
+	}
+
+	out := tx.Outpoints[index]
+	root := &Hub{TxHash: tx.TxID, Amount: out.Value, Vout: out.TxIndex}
+	root.setScript(out.PkScriptData)
+	root.setLabels(opts.Labels)
+
+	// A source tx hash is normalized since the source addresses are never
+	// valid hashes.
+	if len(source) == chainhash.MaxHashStringSize {
+		if hash, err := chainhash.NewHashFromStr(source); err == nil {
+			source = hash.String()
+		}
+	}
+
+	isSource := func(h *Hub) bool {
+		for _, addr := range h.Addresses {
+			if addr == source {
+				return true
+			}
+		}
+		return h.TxHash != "" && h.TxHash == source
+	}
+
+	path := &ProbablePath{Source: source}
+
+	var seq int
+	queue := &pathQueue{{hub: root, depth: 1, probability: 1, poi: 1}}
+	expanded := make(map[outpoint]bool)
+
+	for queue.Len() > 0 {
+		node := heap.Pop(queue).(*pathNode)
+		h := node.hub
+
+		if isSource(h) {
+			path.setHops(node)
+			return path, tx.BlockTime, nil
+		}
+
+		op := outpoint{txHash: h.TxHash, vout: h.Vout}
+		if node.depth > maxDepth || h.TxHash == "" || expanded[op] {
+			continue
+		}
+
+		// A labeled output other than the searched one ends the path if
+		// required.
+		if opts.StopAtLabels && node.depth > 1 && len(h.Labels) > 0 {
+			continue
+		}
+
+		// The first path reaching an output is its most probable path.
+		expanded[op] = true
+		path.Expanded++
+
+		if err = h.getDepth(client, opts, node.poi); err != nil {
+			return nil, tx.BlockTime, err
+		}
+
+		for _, set := range h.Matched {
+			for _, input := range set.Inputs {
+				seq++
+				heap.Push(queue, &pathNode{
+					hub:         input,
+					parent:      node,
+					depth:       node.depth + 1,
+					probability: node.probability * h.LevelProbability,
+					poi:         set.PathPercentOfInputs,
+					seq:         seq,
+				})
+			}
+		}
+	}
+
+	return nil, tx.BlockTime, rpcutils.NewError(rpcutils.ErrPathNotFound,
+		fmt.Sprintf("no path from %s:%d to %s found within a depth of %d", tx.TxID,
+			index, source, maxDepth))
+}
+
+// setHops sets the path hops from the searched output to the source output
+// node.
+func (p *ProbablePath) setHops(node *pathNode) {
+	p.Probability = roundOff(node.probability)
+	p.PathPercentOfInputs = roundOff(node.poi)
+
+	for n := node; n != nil; n = n.parent {
+		p.Hops = append([]*PathHop{{
+			TxHash:              n.hub.TxHash,
+			Vout:                n.hub.Vout,
+			Amount:              n.hub.Amount,
+			Addresses:           n.hub.Addresses,
+			PathProbability:     roundOff(n.probability),
+			PathPercentOfInputs: roundOff(n.poi),
+		}}, p.Hops...)
+	}
+}
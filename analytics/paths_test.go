@@ -0,0 +1,72 @@
+package analytics
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// TestMostProbablePath tests the most probable paths found from the recorded
+// tx outputs and that fewer outputs are analyzed than the chain discovers.
+func TestMostProbablePath(t *testing.T) {
+	type testData struct {
+		Name    string
+		Index   int
+		Source  string
+		Options ChainOptions
+		Hops    int
+		Code    rpcutils.ErrorCode
+	}
+
+	td := []testData{
+		{Name: "path_source_tx", Index: 1, Hops: 2,
+			Source: "1bf3ac90ff68c46fa6d3a81509e885ab8149dbd329179854a1b720e427447c50"},
+		{Name: "path_source_address", Index: 1, Hops: 3,
+			Source: "TsZXXprc8dCQXLpNRatG9nP2MUY7dV2Angp"},
+		{Index: 1, Source: "TsZXXprc8dCQXLpNRatG9nP2MUY7dV2Angp",
+			Options: ChainOptions{MaxDepth: 1}, Code: rpcutils.ErrPathNotFound},
+		{Index: 1, Code: rpcutils.ErrInvalidRequest},
+		{Index: 9, Source: "TsUnknown", Code: rpcutils.ErrInvalidOutputIndex},
+	}
+
+	client := rpcutils.NewReplayer(replayDir)
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			path, txTime, err := MostProbablePath(client, replayTxID, data.Index, data.Source,
+				data.Options)
+			if data.Name == "" {
+				if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != data.Code {
+					t.Fatalf("expected a %v error but found %v", data.Code, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error to be returned but found %v", err)
+			}
+
+			if txTime != 1631634800 {
+				t.Fatalf("expected the tx time to be 1631634800 but found %d", txTime)
+			}
+
+			if len(path.Hops) != data.Hops {
+				t.Fatalf("expected %d hops but found %d", data.Hops, len(path.Hops))
+			}
+
+			chain, _, err := ChainDiscoveryWithOptions(client, replayTxID,
+				ChainOptions{MaxDepth: data.Hops}, data.Index)
+			if err != nil {
+				t.Fatalf("expected no error to be returned but found %v", err)
+			}
+
+			if _, hubs := hubStats(chain[0]); path.Expanded >= hubs {
+				t.Fatalf("expected fewer than %d outputs analyzed but found %d", hubs,
+					path.Expanded)
+			}
+
+			checkGolden(t, data.Name, path)
+		})
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// The shares of the privacy score held by each privacy factor. They sum to 1.
+const (
+	linkabilityWeight  = 0.4
+	anonymitySetWeight = 0.3
+	addressReuseWeight = 0.15
+	sourceHopsWeight   = 0.15
+)
+
+// The privacy factors names.
+const (
+	FactorLinkability  = "linkability"
+	FactorAnonymitySet = "anonymity_set"
+	FactorAddressReuse = "address_reuse"
+	FactorSourceHops   = "source_hops"
+)
+
+// PrivacyFactor is a privacy factor that lowered the privacy score of an
+// output. Penalty is the share of the score it took off.
+type PrivacyFactor struct {
+	Name    string
+	Penalty float64
+	Reason  string
+}
+
+// PrivacyScore rates how hard tracing an output back to its source of funds is,
+// from 0 (fully traceable) to 1. LinkingProbability is the probability of the
+// output being linked to its funding inputs and AnonymitySet the largest number
+// of same amount outputs the output funds were mixed with on their paths.
+// ReusedAddresses lists the output addresses paid to by the funding outputs.
+// Source is the labeled entity or the coinbase the output is deterministically
+// funded by, SourceHops hops back. Factors lists the factors that lowered the
+// score, the largest penalty first.
+type PrivacyScore struct {
+	Vout               uint32
+	Amount             float64
+	Score              float64
+	LinkingProbability float64
+	AnonymitySet       int
+	ReusedAddresses    []string         `json:",omitempty"`
+	Source             string           `json:",omitempty"`
+	SourceHops         int              `json:",omitempty"`
+	Factors            []*PrivacyFactor `json:",omitempty"`
+}
+
+// coinbaseSource is the source name of the coinbase and stakebase inputs.
+const coinbaseSource = "coinbase/stakebase"
+
+// OutputPrivacy returns the privacy score of the output of the chain root hub.
+// It combines the output linking probability, the anonymity set inherited from
+// the mixes found on the output paths, the reuse of the output addresses by the
+// funding outputs and the number of certain hops back to a labeled or a
+// coinbase source. The anonymity set and the reused addresses only consider the
+// hubs discovered in the chain thus a shallower chain may score higher. The
+// source is looked up through the client past the chain discovered, with the
+// labeler used to name the labeled entities.
+func OutputPrivacy(client rpcutils.TxSource, root *Hub, labels Labeler) (
+	*PrivacyScore, error) {
+	p := &PrivacyScore{
+		Vout:               root.Vout,
+		Amount:             root.Amount,
+		LinkingProbability: root.LevelProbability,
+		AnonymitySet:       anonymitySet(root),
+		ReusedAddresses:    reusedAddresses(root),
+	}
+
+	var err error
+	p.Source, p.SourceHops, err = deterministicSource(client, root, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	score := 1.0
+	penalize := func(name string, weight, privacy float64, reason string) {
+		if privacy >= 1 {
+			return
+		}
+
+		penalty := roundOff(weight * (1 - privacy))
+		score -= weight * (1 - privacy)
+		p.Factors = append(p.Factors, &PrivacyFactor{Name: name, Penalty: penalty,
+			Reason: reason})
+	}
+
+	// The linking probability is not set if the output could not be analyzed.
+	if p.LinkingProbability > 0 {
+		penalize(FactorLinkability, linkabilityWeight, 1-p.LinkingProbability,
+			fmt.Sprintf("The output is linked to its funding inputs with a %s probability",
+				formatPercent(p.LinkingProbability)))
+	}
+
+	reason := fmt.Sprintf("The output funds were mixed among at most %d outputs of "+
+		"the same amount", p.AnonymitySet)
+	if p.AnonymitySet == 1 {
+		reason = "The output funds were not mixed with outputs of the same amount"
+	}
+	penalize(FactorAnonymitySet, anonymitySetWeight, 1-1/float64(p.AnonymitySet), reason)
+
+	if len(p.ReusedAddresses) > 0 {
+		penalize(FactorAddressReuse, addressReuseWeight, 0,
+			fmt.Sprintf("The output address %s is reused by the funding outputs",
+				strings.Join(p.ReusedAddresses, ", ")))
+	}
+
+	if p.Source != "" {
+		reason = fmt.Sprintf("The output is deterministically funded by %s %d hop(s) back",
+			p.Source, p.SourceHops)
+		if p.SourceHops == 0 {
+			reason = "The output pays to the labeled entity " + p.Source
+		}
+		penalize(FactorSourceHops, sourceHopsWeight, 1-1/float64(p.SourceHops+1), reason)
+	}
+
+	sort.SliceStable(p.Factors, func(i, j int) bool {
+		return p.Factors[i].Penalty > p.Factors[j].Penalty
+	})
+
+	p.Score = roundOff(score)
+	return p, nil
+}
+
+// anonymitySet returns the largest number of same amount outputs in the txs of
+// the hubs of the chain starting at the hub. It is at least 1.
+func anonymitySet(h *Hub) int {
+	set := 1
+	if h.duplicates > set {
+		set = h.duplicates
+	}
+
+	for _, s := range h.Matched {
+		for _, input := range s.Inputs {
+			if n := anonymitySet(input); n > set {
+				set = n
+			}
+		}
+	}
+	return set
+}
+
+// reusedAddresses returns the root hub addresses that the other hubs of the
+// chain pay to.
+func reusedAddresses(root *Hub) []string {
+	addrs := make(map[string]bool, len(root.Addresses))
+	for _, addr := range root.Addresses {
+		addrs[addr] = false
+	}
+
+	var walk func(h *Hub)
+	walk = func(h *Hub) {
+		for _, s := range h.Matched {
+			for _, input := range s.Inputs {
+				for _, addr := range input.Addresses {
+					if _, ok := addrs[addr]; ok {
+						addrs[addr] = true
+					}
+				}
+				walk(input)
+			}
+		}
+	}
+	walk(root)
+
+	var reused []string
+	for _, addr := range root.Addresses {
+		if addrs[addr] {
+			reused = append(reused, addr)
+		}
+	}
+	return reused
+}
+
+// maxSourceHops is the number of certain links followed back from an output
+// when looking for its deterministic source of funds.
+const maxSourceHops = DefaultTraceDepth
+
+// deterministicSource returns the name of the closest labeled entity or
+// coinbase reached from the hub through certain links only and the number of
+// hops to it. The certain links are followed up to maxSourceHops hops back
+// whatever the depth of the discovered chain, thus the hubs not analyzed yet
+// are analyzed on copies that leave the chain untouched. An empty name is
+// returned if none is reached.
+func deterministicSource(client rpcutils.TxSource, root *Hub, labels Labeler) (
+	string, int, error) {
+	level := []*Hub{root}
+	for hops := 0; len(level) > 0 && hops <= maxSourceHops; hops++ {
+		var next []*Hub
+		for _, h := range level {
+			if h.TxHash == "" && hops > 0 {
+				return coinbaseSource, hops, nil
+			}
+
+			if len(h.Labels) > 0 {
+				return h.Labels[0].Entity, hops, nil
+			}
+
+			if hops == maxSourceHops || h.TxHash == "" {
+				continue
+			}
+
+			if !h.analyzed() {
+				c := &Hub{TxHash: h.TxHash, Amount: h.Amount, Vout: h.Vout,
+					nullData: h.nullData}
+				if err := c.getDepth(client, ChainOptions{Labels: labels}, 1); err != nil {
+					return "", 0, err
+				}
+				h = c
+			}
+
+			// A linking probability of 1 means a single set funds the output.
+			if h.LevelProbability == 1 && len(h.Matched) == 1 {
+				next = append(next, h.Matched[0].Inputs...)
+			}
+		}
+		level = next
+	}
+	return "", 0, nil
+}
+
+// analyzed checks if the hub funding sets were looked up.
+func (h *Hub) analyzed() bool {
+	return h.LevelProbability > 0 || len(h.Matched) > 0 || h.StatusMsg != ""
+}
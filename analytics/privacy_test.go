@@ -0,0 +1,133 @@
+package analytics
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// TestOutputPrivacy tests that the output privacy score is lowered by each
+// privacy factor.
+func TestOutputPrivacy(t *testing.T) {
+	type testData struct {
+		Root         *Hub
+		Score        float64
+		AnonymitySet int
+		Reused       []string
+		Source       string
+		SourceHops   int
+		Factors      []string
+	}
+
+	// hub returns a hub funded by a single set of the inputs.
+	hub := func(txHash string, level float64, addr string, inputs ...*Hub) *Hub {
+		h := &Hub{TxHash: txHash, LevelProbability: level, Addresses: []string{addr}}
+		if len(inputs) > 0 {
+			h.Matched = []Set{{Inputs: inputs}}
+		}
+		return h
+	}
+
+	labeled := hub("aa", 0, "TsExchange")
+	labeled.Labels = []*labels.Label{exchangeLabel}
+
+	mixed := hub("bb", 0.25, "TsMix", hub("cc", 0, "TsOther"))
+	mixed.duplicates = 4
+
+	td := []testData{
+		{
+			Root:         hub("01", 0.5, "TsUser", hub("02", 0, "TsOther")),
+			Score:        0.5,
+			AnonymitySet: 1,
+			Factors:      []string{FactorAnonymitySet, FactorLinkability},
+		},
+		{
+			Root:         hub("01", 1, "TsUser", hub("02", 1, "TsUser", labeled)),
+			Score:        0.1,
+			AnonymitySet: 1,
+			Reused:       []string{"TsUser"},
+			Source:       "Exchange A",
+			SourceHops:   2,
+			Factors: []string{FactorLinkability, FactorAnonymitySet, FactorAddressReuse,
+				FactorSourceHops},
+		},
+		{
+			Root:         hub("01", 1, "TsUser", hub("", 0, "")),
+			Score:        0.225,
+			AnonymitySet: 1,
+			Source:       coinbaseSource,
+			SourceHops:   1,
+			Factors:      []string{FactorLinkability, FactorAnonymitySet, FactorSourceHops},
+		},
+		{
+			Root:         hub("01", 0.5, "TsUser", mixed),
+			Score:        0.725,
+			AnonymitySet: 4,
+			Factors:      []string{FactorLinkability, FactorAnonymitySet},
+		},
+		{
+			Root:         labeled,
+			Score:        0.55,
+			AnonymitySet: 1,
+			Source:       "Exchange A",
+			Factors:      []string{FactorAnonymitySet, FactorSourceHops},
+		},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			p, err := OutputPrivacy(nil, data.Root, nil)
+			if err != nil {
+				t.Fatalf("expected no error but found: %v", err)
+			}
+
+			if p.Score != data.Score || p.AnonymitySet != data.AnonymitySet {
+				t.Fatalf("expected the score %v and anonymity set %d but found %v and %d",
+					data.Score, data.AnonymitySet, p.Score, p.AnonymitySet)
+			}
+
+			if !reflect.DeepEqual(p.ReusedAddresses, data.Reused) {
+				t.Fatalf("expected the reused addresses %v but found %v", data.Reused,
+					p.ReusedAddresses)
+			}
+
+			if p.Source != data.Source || p.SourceHops != data.SourceHops {
+				t.Fatalf("expected the source %q %d hop(s) back but found %q %d hop(s) back",
+					data.Source, data.SourceHops, p.Source, p.SourceHops)
+			}
+
+			factors := make([]string, len(p.Factors))
+			for k, f := range p.Factors {
+				factors[k] = f.Name
+			}
+
+			if !reflect.DeepEqual(factors, data.Factors) {
+				t.Fatalf("expected the factors %v but found %v", data.Factors, factors)
+			}
+		})
+	}
+}
+
+// TestOutputPrivacyPastChain tests that the source of an output certainly
+// linked to its input is found past the chain discovered.
+func TestOutputPrivacyPastChain(t *testing.T) {
+	client := rpcutils.NewReplayer(replayDir)
+
+	for _, opts := range []ChainOptions{{Privacy: true}, {MaxDepth: 1, Privacy: true},
+		{DeterministicOnly: true, Privacy: true}} {
+		chain, _, err := ChainDiscoveryWithOptions(client, replayTxID, opts, 0)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		p := chain[0].Privacy
+		if p.LinkingProbability != 1 || p.Source != coinbaseSource ||
+			p.SourceHops != 2 || p.Score != 0.25 {
+			t.Fatalf("expected the %+v options to find the %s source 2 hop(s) back "+
+				"with a 0.25 score but found %+v", opts, coinbaseSource, p)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+const (
+	// SortByBuckets sorts the solutions by their number of buckets.
+	SortByBuckets = "buckets"
+
+	// SortByFees sorts the solutions by how unevenly the tx fee is distributed
+	// across their buckets i.e. by the standard deviation of the buckets fees.
+	SortByFees = "fees"
+)
+
+// SolutionsQuery defines how the raw solutions are filtered, sorted and paged.
+// The zero value returns all the solutions in their original order.
+type SolutionsQuery struct {
+	// Input and Output keep only the solutions with a bucket that holds the
+	// input or the output amount. Zero amounts are ignored.
+	Input  float64
+	Output float64
+
+	// SortBy is either empty, SortByBuckets or SortByFees.
+	SortBy string
+	Desc   bool
+
+	// Page is the 1-based page number of Limit solutions returned. A zero
+	// Limit returns all the solutions.
+	Page  int
+	Limit int
+}
+
+// SolutionsPage holds a page of the raw solutions and the total number of
+// solutions that matched the filters. Entropy holds the metrics of the tx
+// computed from all its solutions if set by the caller.
+type SolutionsPage struct {
+	Solutions []*AllFundsFlows
+	Total     int
+	Page      int        `json:",omitempty"`
+	Limit     int        `json:",omitempty"`
+	Entropy   *TxEntropy `json:",omitempty"`
+}
+
+// QuerySolutions filters, sorts and pages the raw solutions as defined by the
+// query. An ErrInvalidRequest error is returned if the query is invalid.
+func QuerySolutions(solutions []*AllFundsFlows, q SolutionsQuery) (*SolutionsPage, error) {
+	if q.Page < 0 || q.Limit < 0 {
+		return nil, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			"page and limit should not be negative")
+	}
+
+	var less func(a, b *AllFundsFlows) bool
+	switch q.SortBy {
+	case "":
+	case SortByBuckets:
+		less = func(a, b *AllFundsFlows) bool {
+			return len(a.FundsFlow) < len(b.FundsFlow)
+		}
+	case SortByFees:
+		less = func(a, b *AllFundsFlows) bool {
+			return feesDeviation(a) < feesDeviation(b)
+		}
+	default:
+		return nil, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			fmt.Sprintf("invalid sort %q: expected %s or %s", q.SortBy, SortByBuckets,
+				SortByFees))
+	}
+
+	filtered := make([]*AllFundsFlows, 0, len(solutions))
+	for _, sol := range solutions {
+		if (q.Input == 0 || hasAmount(sol, q.Input, true)) &&
+			(q.Output == 0 || hasAmount(sol, q.Output, false)) {
+			filtered = append(filtered, sol)
+		}
+	}
+
+	if less != nil {
+		sort.SliceStable(filtered, func(i, j int) bool {
+			if q.Desc {
+				return less(filtered[j], filtered[i])
+			}
+			return less(filtered[i], filtered[j])
+		})
+	}
+
+	page := &SolutionsPage{Total: len(filtered), Limit: q.Limit}
+	if q.Limit == 0 {
+		page.Solutions = filtered
+		return page, nil
+	}
+
+	page.Page = q.Page
+	if page.Page == 0 {
+		page.Page = 1
+	}
+
+	// The pages past the last one are empty. They are checked before the page
+	// start is computed so that it cannot overflow.
+	if len(filtered) == 0 || page.Page-1 > (len(filtered)-1)/q.Limit {
+		page.Solutions = filtered[len(filtered):]
+		return page, nil
+	}
+
+	start := (page.Page - 1) * q.Limit
+	end := len(filtered)
+	if q.Limit < end-start {
+		end = start + q.Limit
+	}
+
+	page.Solutions = filtered[start:end]
+	return page, nil
+}
+
+// hasAmount checks if any of the solution buckets holds the amount in its
+// inputs or in its matched outputs.
+func hasAmount(sol *AllFundsFlows, amount float64, isInput bool) bool {
+	for _, bucket := range sol.FundsFlow {
+		values := bucket.MatchedOutputs.Values
+		if isInput {
+			values = bucket.Inputs.Values
+		}
+
+		for _, v := range values {
+			if roundOff(v) == roundOff(amount) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// feesDeviation returns the standard deviation of the solution buckets fees.
+func feesDeviation(sol *AllFundsFlows) float64 {
+	if len(sol.FundsFlow) == 0 {
+		return 0
+	}
+
+	var mean float64
+	for _, bucket := range sol.FundsFlow {
+		mean += bucket.Fee
+	}
+	mean /= float64(len(sol.FundsFlow))
+
+	var variance float64
+	for _, bucket := range sol.FundsFlow {
+		variance += (bucket.Fee - mean) * (bucket.Fee - mean)
+	}
+
+	return math.Sqrt(variance / float64(len(sol.FundsFlow)))
+}
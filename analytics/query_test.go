@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// TestQuerySolutions tests the filtering, sorting and paging of the raw
+// solutions.
+func TestQuerySolutions(t *testing.T) {
+	bucket := func(fee float64, in, out []float64) TxFundsFlow {
+		return TxFundsFlow{
+			Fee:            fee,
+			Inputs:         GroupedValues{Values: in},
+			MatchedOutputs: GroupedValues{Values: out},
+		}
+	}
+
+	solutions := []*AllFundsFlows{
+		{Solution: 1, FundsFlow: []TxFundsFlow{
+			bucket(0.2, []float64{1, 2}, []float64{2.8}),
+			bucket(0, []float64{3}, []float64{3}),
+		}},
+		{Solution: 2, FundsFlow: []TxFundsFlow{
+			bucket(0.1, []float64{1}, []float64{0.9}),
+			bucket(0.1, []float64{2}, []float64{1.9}),
+			bucket(0, []float64{3}, []float64{3}),
+		}},
+		{Solution: 3, FundsFlow: []TxFundsFlow{
+			bucket(0.2, []float64{1, 2, 3}, []float64{5.8}),
+		}},
+	}
+
+	type testData struct {
+		Query    SolutionsQuery
+		Expected []int
+		Total    int
+		IsErr    bool
+	}
+
+	td := []testData{
+		{Query: SolutionsQuery{}, Expected: []int{1, 2, 3}, Total: 3},
+		{Query: SolutionsQuery{SortBy: SortByBuckets}, Expected: []int{3, 1, 2}, Total: 3},
+		{Query: SolutionsQuery{SortBy: SortByBuckets, Desc: true}, Expected: []int{2, 1, 3}, Total: 3},
+		{Query: SolutionsQuery{SortBy: SortByFees}, Expected: []int{3, 2, 1}, Total: 3},
+		{Query: SolutionsQuery{Input: 3}, Expected: []int{1, 2, 3}, Total: 3},
+		{Query: SolutionsQuery{Output: 3}, Expected: []int{1, 2}, Total: 2},
+		{Query: SolutionsQuery{Input: 2, Output: 1.9}, Expected: []int{2}, Total: 1},
+		{Query: SolutionsQuery{Output: 7}, Expected: []int{}, Total: 0},
+		{Query: SolutionsQuery{Limit: 2}, Expected: []int{1, 2}, Total: 3},
+		{Query: SolutionsQuery{Page: 2, Limit: 2}, Expected: []int{3}, Total: 3},
+		{Query: SolutionsQuery{Page: 3, Limit: 2}, Expected: []int{}, Total: 3},
+		{Query: SolutionsQuery{Page: 1<<61 + 1, Limit: 4}, Expected: []int{}, Total: 3},
+		{Query: SolutionsQuery{Page: 1, Limit: math.MaxInt64}, Expected: []int{1, 2, 3}, Total: 3},
+		{Query: SolutionsQuery{Output: 7, Page: 2, Limit: 2}, Expected: []int{}, Total: 0},
+		{Query: SolutionsQuery{SortBy: "size"}, IsErr: true},
+		{Query: SolutionsQuery{Limit: -1}, IsErr: true},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			page, err := QuerySolutions(solutions, data.Query)
+			if data.IsErr {
+				if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != rpcutils.ErrInvalidRequest {
+					t.Fatalf("expected an invalid request error but found %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but found %v", err)
+			}
+
+			result := make([]int, len(page.Solutions))
+			for k, sol := range page.Solutions {
+				result[k] = sol.Solution
+			}
+
+			if !reflect.DeepEqual(result, data.Expected) {
+				t.Fatalf("expected solutions %v but found %v", data.Expected, result)
+			}
+
+			if page.Total != data.Total {
+				t.Fatalf("expected the total to be %d but found %d", data.Total, page.Total)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+	"github.com/raedahgroup/dcrchainanalysis/v1/networkconfig"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// AnalyzeTransaction runs the funds flow analysis on the provided transaction
+// data returning the fee details, the raw solutions, the funds flow
+// probabilities, the deterministic links, the link matrix and the entropy
+// metrics.
+func AnalyzeTransaction(tx *rpcutils.Transaction) (*TxAnalysis, error) {
+	rawSolution, inputs, outputs, err := TransactionFundsFlow(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	links := TxLinkMatrix(rawSolution, inputs, outputs)
+
+	return &TxAnalysis{
+		TxID:               tx.TxID,
+		Fee:                newTxFee(tx),
+		Solutions:          rawSolution,
+		Probabilities:      TxFundsFlowProbability(rawSolution, inputs, outputs),
+		DeterministicLinks: TxDeterministicLinks(rawSolution),
+		LinkMatrix:         links,
+		Entropy:            NewTxEntropy(links),
+		Ticket:             AnalyzeTicket(tx),
+	}, nil
+}
+
+// AnalyzeRawTx decodes the serialized transaction hex and runs the funds flow
+// analysis on it. The transaction does not need to be on chain. prevOutAmounts
+// should hold the amounts of the previous outpoints spent by the transaction
+// inputs in their respective order. If prevOutAmounts is empty the amounts are
+// fetched from the client. An ErrInvalidRequest error is returned if an amount
+// is negative or if the inputs total is below the outputs total.
+func AnalyzeRawTx(client rpcutils.TxSource, txHex string, prevOutAmounts []float64,
+	activeNet networkconfig.NetworkType) (*TxAnalysis, *rpcutils.Transaction, error) {
+	msgTx, err := rpcutils.DecodeRawTx(txHex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(prevOutAmounts) == 0 {
+		prevOutAmounts, err = fetchPrevOutAmounts(client, msgTx)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(prevOutAmounts) != len(msgTx.TxIn) {
+		return nil, nil, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			fmt.Sprintf("expected %d previous outpoint amounts but found %d",
+				len(msgTx.TxIn), len(prevOutAmounts)))
+	}
+
+	if err = checkPrevOutAmounts(msgTx, prevOutAmounts); err != nil {
+		return nil, nil, err
+	}
+
+	tx := rpcutils.ExtractMsgTxTransaction(msgTx, prevOutAmounts, activeNet)
+
+	log.Infof("Analyzing the raw transaction %s", tx.TxID)
+
+	analysis, err := AnalyzeTransaction(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return analysis, tx, nil
+}
+
+// AnalyzeBlock runs the funds flow analysis on all the block transactions
+// except the coinbase. The previous outpoints amounts are read from the amount
+// values of the transactions inputs serialized in the block.
+func AnalyzeBlock(block *wire.MsgBlock, activeNet networkconfig.NetworkType) (
+	[]*TxAnalysis, error) {
+	txs := make([]*wire.MsgTx, 0, len(block.Transactions)+len(block.STransactions))
+	txs = append(txs, block.Transactions...)
+	txs = append(txs, block.STransactions...)
+	analyses := make([]*TxAnalysis, 0, len(txs))
+
+	for i, msgTx := range txs {
+		// The first regular tree transaction is the coinbase.
+		if i == 0 {
+			continue
+		}
+
+		amountsIn := make([]float64, len(msgTx.TxIn))
+		for k, in := range msgTx.TxIn {
+			amountsIn[k] = dcrutil.Amount(in.ValueIn).ToCoin()
+		}
+
+		tx := rpcutils.ExtractMsgTxTransaction(msgTx, amountsIn, activeNet)
+		tx.BlockTime = block.Header.Timestamp.Unix()
+
+		analysis, err := AnalyzeTransaction(tx)
+		if err != nil {
+			return nil, fmt.Errorf("analyzing tx %s failed: %v", tx.TxID, err)
+		}
+
+		analyses = append(analyses, analysis)
+	}
+
+	return analyses, nil
+}
+
+// checkPrevOutAmounts checks that the previous outpoints amounts are valid
+// amounts that fund all the transaction outputs.
+func checkPrevOutAmounts(msgTx *wire.MsgTx, prevOutAmounts []float64) error {
+	var totalIn, totalOut dcrutil.Amount
+	for i, value := range prevOutAmounts {
+		amount, err := dcrutil.NewAmount(value)
+		if err != nil || amount < 0 {
+			return rpcutils.NewError(rpcutils.ErrInvalidRequest,
+				fmt.Sprintf("invalid previous outpoint amount %v of input %d", value, i))
+		}
+		totalIn += amount
+	}
+
+	for _, out := range msgTx.TxOut {
+		totalOut += dcrutil.Amount(out.Value)
+	}
+
+	if totalIn < totalOut {
+		return rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			fmt.Sprintf("the inputs total %v is below the outputs total %v", totalIn,
+				totalOut))
+	}
+	return nil
+}
+
+// fetchPrevOutAmounts retrieves the amounts of the previous outpoints spent by
+// the transaction inputs. Inputs without a previous outpoint i.e. coinbase and
+// stakebase inputs use the input amount value set in the transaction.
+func fetchPrevOutAmounts(client rpcutils.TxSource, msgTx *wire.MsgTx) ([]float64, error) {
+	if client == nil {
+		return nil, rpcutils.NewError(rpcutils.ErrInvalidRequest, "previous outpoint "+
+			"amounts are needed when no transactions source is available")
+	}
+
+	amounts := make([]float64, len(msgTx.TxIn))
+
+	for i, in := range msgTx.TxIn {
+		prevOut := in.PreviousOutPoint
+		if prevOut.Hash == (chainhash.Hash{}) {
+			amounts[i] = dcrutil.Amount(in.ValueIn).ToCoin()
+			continue
+		}
+
+		prevTx, err := RetrieveTxData(client, prevOut.Hash.String())
+		if err != nil {
+			return nil, err
+		}
+
+		if int(prevOut.Index) >= len(prevTx.Outpoints) {
+			return nil, rpcutils.NewError(rpcutils.ErrInvalidOutputIndex,
+				fmt.Sprintf("previous outpoint %s:%d does not exist", prevOut.Hash,
+					prevOut.Index))
+		}
+
+		amounts[i] = prevTx.Outpoints[prevOut.Index].Value
+	}
+	return amounts, nil
+}
@@ -0,0 +1,121 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/raedahgroup/dcrchainanalysis/v1/networkconfig"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// testRawTx is a serialized testnet transaction with the same input and output
+// amounts as the transaction used in TestTransactionFundsFlow.
+const (
+	testRawTxID = "9daffa4ed29257eb8cc170bf0bbb3a114d2e38961ca968ee5da9c8490d352c38"
+	testRawTx   = "01000000038fe7042d078343dc3b01f8c9c905b867c0e50337d8c55c25f5d4add5" +
+		"51e83c080000000000ffffffffe7644b840cc8a171cc85ac02c1042616eeb0423bf073" +
+		"9bdaa103f98513a5caea0100000000ffffffff01d5f72cbffdf27e2e410cd03f3a6534" +
+		"7290b564b4e9d1e3a3aa2d26c0dfee2e0200000000ffffffff04adf73bee0000000000" +
+		"001976a9143c8eed4311e04776d23426b4da32d89df0db187388acbac64df400000000" +
+		"00001976a914d5a6063b8dabaaafd40beaf5c3e211aefcdcc24d88acbac64df4000000" +
+		"0000001976a914afb2599b857425f6df0f13e483c92a91b09f487f88acdbe3f23e7500" +
+		"000000001976a91472070b6afa984b7ed7f76dd3ee38591d02f1024d88ac0000000000" +
+		"00000003599b3cee0000000000000000ffffffff0151bac64df4000000000000000" +
+		"0ffffffff0151690d41337600000000000000ffffffff0151"
+)
+
+// mapSource is a TxSource that serves transactions from a map. The missing
+// transactions are reported with the dcrd no tx info error.
+type mapSource map[string]*dcrjson.TxRawResult
+
+func (m mapSource) GetRawTransactionVerbose(txHash *chainhash.Hash) (
+	*dcrjson.TxRawResult, error) {
+	tx, ok := m[txHash.String()]
+	if !ok {
+		return nil, &dcrjson.RPCError{Code: dcrjson.ErrRPCNoTxInfo,
+			Message: "No information available about transaction"}
+	}
+	return tx, nil
+}
+
+// TestAnalyzeRawTx tests the functionality of AnalyzeRawTx function.
+func TestAnalyzeRawTx(t *testing.T) {
+	prevOuts := mapSource{
+		"083ce851d5add4f5255cc5d83703e5c067b805c9c9f8013bdc4383072d04e78f": {
+			Vout: []dcrjson.Vout{{Value: 39.96949337, N: 0}},
+		},
+		"eacaa51385f903a1da9b73f03b42b0ee162604c102ac85cc71a1c80c844b64e7": {
+			Vout: []dcrjson.Vout{{Value: 1, N: 0}, {Value: 40.9873785, N: 1}},
+		},
+		"2eeedfc0262daaa3e3d1e9b464b5907234653a3fd00c412e7ef2fdbf2cf7d501": {
+			Vout: []dcrjson.Vout{{Value: 1, N: 0}, {Value: 2, N: 1},
+				{Value: 5076.66042217, N: 2}},
+		},
+	}
+
+	type testData struct {
+		Name    string
+		Source  mapSource
+		Amounts []float64
+		IsError bool
+		Code    rpcutils.ErrorCode
+	}
+
+	td := []testData{
+		{Name: "caller_amounts", Amounts: []float64{39.96949337, 40.9873785, 5076.66042217}},
+		{Name: "source_amounts", Source: prevOuts},
+		{Name: "missing_amounts", Amounts: []float64{39.96949337}, IsError: true,
+			Code: rpcutils.ErrInvalidRequest},
+		{Name: "negative_amount", Amounts: []float64{-5, 40.9873785, 5076.66042217},
+			IsError: true, Code: rpcutils.ErrInvalidRequest},
+		{Name: "amounts_below_outputs", Amounts: []float64{1, 1, 1}, IsError: true,
+			Code: rpcutils.ErrInvalidRequest},
+		{Name: "unknown_prevouts", Source: mapSource{}, IsError: true,
+			Code: rpcutils.ErrTxNotFound},
+	}
+
+	for _, data := range td {
+		t.Run(data.Name, func(t *testing.T) {
+			analysis, tx, err := AnalyzeRawTx(data.Source, testRawTx, data.Amounts,
+				networkconfig.TestNet)
+			if data.IsError {
+				if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != data.Code {
+					t.Fatalf("expected a %v error but found %v", data.Code, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error to be returned but found %v", err)
+			}
+
+			if analysis.TxID != testRawTxID || tx.TxID != testRawTxID {
+				t.Fatalf("expected tx %s to be analyzed but found %s", testRawTxID,
+					analysis.TxID)
+			}
+
+			if tx.Fees != 0.000672 {
+				t.Fatalf("expected the tx fee to be 0.000672 but found %v", tx.Fees)
+			}
+
+			if len(analysis.Solutions) != 1 || len(analysis.Solutions[0].FundsFlow) != 3 {
+				t.Fatalf("expected one solution with 3 buckets but found %v",
+					analysis.Solutions)
+			}
+
+			if len(analysis.Probabilities) != 3 {
+				t.Fatalf("expected 3 unique outputs probabilities but found %d",
+					len(analysis.Probabilities))
+			}
+		})
+	}
+}
+
+// TestAnalyzeRawTxInvalidHex tests that an invalid tx hex returns an error.
+func TestAnalyzeRawTxInvalidHex(t *testing.T) {
+	_, _, err := AnalyzeRawTx(nil, "0100zz", []float64{1}, networkconfig.TestNet)
+	if err == nil {
+		t.Fatal("expected an error to be returned but none was returned")
+	}
+}
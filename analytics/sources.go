@@ -0,0 +1,125 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
+)
+
+// SourceSummary aggregates the chains leaf hubs funded by the same addresses.
+// Amount is the total amount of the leaf outputs reached. PathProbability and
+// Depth are the amount weighted path probability and depth of the leaves.
+// Multisig outputs are grouped by the full list of their addresses.
+type SourceSummary struct {
+	Addresses       []string
+	Labels          []*labels.Label `json:",omitempty"`
+	Amount          float64
+	PathProbability float64
+	Depth           float64
+	Sources         int
+}
+
+// outpoint identifies a tx output.
+type outpoint struct {
+	txHash string
+	vout   uint32
+}
+
+// leafSource is a leaf hub reached by the chains with the highest probability
+// and the lowest depth it was reached at.
+type leafSource struct {
+	hub         *Hub
+	probability float64
+	depth       int
+}
+
+// ChainSources returns the summary of the leaf hubs of the chains grouped by
+// their addresses and sorted by the amount reached, largest first. A leaf hub
+// reached by several paths is only counted once. Leaf hubs without addresses,
+// such as the coinbase and the stakebase inputs, are skipped.
+func ChainSources(chain []*Hub) []*SourceSummary {
+	leaves := make(map[outpoint]*leafSource)
+	for _, root := range chain {
+		for _, set := range root.Matched {
+			for _, input := range set.Inputs {
+				collectSources(input, root.PathProbability, 1, leaves)
+			}
+		}
+	}
+
+	summaries := make(map[string]*SourceSummary)
+	probabilities := make(map[string]float64)
+	depths := make(map[string]float64)
+
+	for _, leaf := range leaves {
+		key := strings.Join(leaf.hub.Addresses, ",")
+
+		s, ok := summaries[key]
+		if !ok {
+			s = &SourceSummary{Addresses: leaf.hub.Addresses, Labels: leaf.hub.Labels}
+			summaries[key] = s
+		}
+
+		s.Amount += leaf.hub.Amount
+		s.Sources++
+		probabilities[key] += leaf.hub.Amount * leaf.probability
+		depths[key] += leaf.hub.Amount * float64(leaf.depth)
+	}
+
+	sources := make([]*SourceSummary, 0, len(summaries))
+	for key, s := range summaries {
+		if s.Amount > 0 {
+			s.PathProbability = roundOff(probabilities[key] / s.Amount)
+			s.Depth = roundOff(depths[key] / s.Amount)
+		}
+		s.Amount = roundOff(s.Amount)
+		sources = append(sources, s)
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Amount == sources[j].Amount {
+			return strings.Join(sources[i].Addresses, ",") <
+				strings.Join(sources[j].Addresses, ",")
+		}
+		return sources[i].Amount > sources[j].Amount
+	})
+
+	return sources
+}
+
+// collectSources appends the leaf hubs of the chain starting at the hub to the
+// leaves. probability is the path probability of the hub funding the chain
+// root output and depth the number of hubs between the hub and the root.
+func collectSources(h *Hub, probability float64, depth int, leaves map[outpoint]*leafSource) {
+	if len(h.Matched) > 0 {
+		for _, set := range h.Matched {
+			for _, input := range set.Inputs {
+				collectSources(input, h.PathProbability, depth+1, leaves)
+			}
+		}
+		return
+	}
+
+	if len(h.Addresses) == 0 {
+		return
+	}
+
+	key := outpoint{txHash: h.TxHash, vout: h.Vout}
+	leaf, ok := leaves[key]
+	if !ok {
+		leaves[key] = &leafSource{hub: h, probability: probability, depth: depth}
+		return
+	}
+
+	if probability > leaf.probability {
+		leaf.probability = probability
+	}
+
+	if depth < leaf.depth {
+		leaf.depth = depth
+	}
+}
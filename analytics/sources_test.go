@@ -0,0 +1,51 @@
+package analytics
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestChainSources tests the aggregation of the chains leaf hubs by address.
+func TestChainSources(t *testing.T) {
+	leafA := &Hub{Addresses: []string{"TsX"}, Amount: 2, TxHash: "a"}
+	chain := []*Hub{
+		{
+			Addresses:       []string{"TsRoot"},
+			Amount:          4.9,
+			TxHash:          "root",
+			PathProbability: 0.5,
+			Matched: []Set{
+				{Inputs: []*Hub{leafA}},
+				{Inputs: []*Hub{
+					{
+						Addresses:       []string{"TsB"},
+						Amount:          6,
+						TxHash:          "b",
+						PathProbability: 0.25,
+						Matched: []Set{{Inputs: []*Hub{
+							{Addresses: []string{"TsX"}, Amount: 1, TxHash: "c"},
+							{Addresses: []string{"TsY", "TsZ"}, Amount: 3, TxHash: "d", ReqSigs: 2},
+							leafA,
+						}}},
+					},
+					{Amount: 1, TxHash: ""},
+				}},
+			},
+		},
+	}
+
+	expected := []*SourceSummary{
+		{Addresses: []string{"TsX"}, Amount: 3, PathProbability: 0.416666667,
+			Depth: 1.333333333, Sources: 2},
+		{Addresses: []string{"TsY", "TsZ"}, Amount: 3, PathProbability: 0.25, Depth: 2,
+			Sources: 1},
+	}
+
+	if result := ChainSources(chain); !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected sources %+v but found %+v", expected, result)
+	}
+
+	if result := ChainSources(nil); len(result) != 0 {
+		t.Fatalf("expected no sources but found %+v", result)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// TaintPolicy defines how the taint of the inputs funding an output is passed
+// on to the output.
+type TaintPolicy string
+
+const (
+	// TaintHaircut taints each output with the share of its funding inputs
+	// value that is tainted.
+	TaintHaircut TaintPolicy = "haircut"
+
+	// TaintPoison fully taints an output funded by any tainted input.
+	TaintPoison TaintPolicy = "poison"
+
+	// TaintFIFO funds the tx outputs in their tx order with the tx inputs in
+	// their tx order, the first input first, thus each output takes the
+	// inputs value the previous outputs left. Only the tainted inputs value
+	// used up by the output taints it.
+	TaintFIFO TaintPolicy = "fifo"
+)
+
+// TaintSources lists the tainted outpoints, as "txhash:vout", and the tainted
+// addresses. An output paying to any of the addresses is tainted.
+type TaintSources struct {
+	Outpoints []string
+	Addresses []string
+}
+
+// TaintResult holds the taint of the target output. Score is the tainted
+// fraction of the output value.
+type TaintResult struct {
+	TxHash        string
+	Vout          uint32
+	Amount        float64
+	Policy        TaintPolicy
+	Score         float64
+	TaintedAmount float64
+	Sources       []*TaintedSource `json:",omitempty"`
+}
+
+// TaintedSource is a tainted output reached by the chain of the target output.
+// Share is the fraction of the target output value it taints and Amount the
+// matching value.
+type TaintedSource struct {
+	TxHash    string
+	Vout      uint32
+	Addresses []string `json:",omitempty"`
+	Amount    float64
+	Share     float64
+}
+
+// taintEngine propagates the taint from the tainted sources to the target
+// output through the chain hubs. The client is only used by the FIFO policy to
+// fetch the hubs txs.
+type taintEngine struct {
+	client    rpcutils.TxSource
+	policy    TaintPolicy
+	outpoints map[outpoint]bool
+	addresses map[string]bool
+	reached   map[outpoint]*TaintedSource
+}
+
+// ComputeTaint returns the taint of the root hub output by the sources as
+// defined by the policy. The root hub is a chain returned by the chain
+// discovery, preferably following the certain links. Taint can only be found
+// within the depth of the chain thus the hubs that were not expanded are
+// treated as untainted. The alternative funding sets of an output are weighted
+// by their PathPercentOfInputs. The FIFO policy fetches the txs of the hubs
+// through the client to order their inputs and outputs. An ErrInvalidRequest
+// error is returned if the policy or the sources are invalid.
+func ComputeTaint(client rpcutils.TxSource, root *Hub, sources TaintSources,
+	policy TaintPolicy) (*TaintResult, error) {
+	if err := ValidateTaint(sources, policy); err != nil {
+		return nil, err
+	}
+
+	e := &taintEngine{
+		client:    client,
+		policy:    policy,
+		outpoints: make(map[outpoint]bool),
+		addresses: make(map[string]bool),
+		reached:   make(map[outpoint]*TaintedSource),
+	}
+
+	// The outpoints were validated above.
+	for _, s := range sources.Outpoints {
+		op, _ := parseOutpoint(s)
+		e.outpoints[op] = true
+	}
+
+	for _, addr := range sources.Addresses {
+		e.addresses[addr] = true
+	}
+
+	if err := e.propagate(root, 1); err != nil {
+		return nil, err
+	}
+
+	result := &TaintResult{
+		TxHash: root.TxHash,
+		Vout:   root.Vout,
+		Amount: root.Amount,
+		Policy: policy,
+	}
+
+	for _, s := range e.reached {
+		// Any contact with a tainted source fully taints the output.
+		if policy == TaintPoison {
+			s.Share = 1
+		}
+
+		s.Share = roundOff(s.Share)
+		s.Amount = roundOff(s.Share * root.Amount)
+		result.Score += s.Share
+		result.Sources = append(result.Sources, s)
+	}
+
+	if result.Score > 1 {
+		result.Score = 1
+	}
+
+	result.Score = roundOff(result.Score)
+	result.TaintedAmount = roundOff(result.Score * root.Amount)
+
+	sort.Slice(result.Sources, func(i, j int) bool {
+		a, b := result.Sources[i], result.Sources[j]
+		if a.Share == b.Share {
+			if a.TxHash == b.TxHash {
+				return a.Vout < b.Vout
+			}
+			return a.TxHash < b.TxHash
+		}
+		return a.Share > b.Share
+	})
+
+	return result, nil
+}
+
+// ValidateTaint checks the taint sources and policy. It allows the taint
+// requests to be rejected before the chain discovery. An ErrInvalidRequest
+// error is returned if either is invalid.
+func ValidateTaint(sources TaintSources, policy TaintPolicy) error {
+	switch policy {
+	case TaintHaircut, TaintPoison, TaintFIFO:
+	default:
+		return rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			fmt.Sprintf("invalid taint policy %q: expected %s, %s or %s", policy,
+				TaintHaircut, TaintPoison, TaintFIFO))
+	}
+
+	if len(sources.Outpoints) == 0 && len(sources.Addresses) == 0 {
+		return rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			"at least one tainted outpoint or address is required")
+	}
+
+	for _, s := range sources.Outpoints {
+		if _, err := parseOutpoint(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// propagate records the tainted sources funding the hub output. weight is the
+// fraction of the target output value funded by the hub output.
+func (e *taintEngine) propagate(h *Hub, weight float64) error {
+	if weight <= 0 {
+		return nil
+	}
+
+	if e.isTainted(h) {
+		op := outpoint{txHash: h.TxHash, vout: h.Vout}
+		s, ok := e.reached[op]
+		if !ok {
+			s = &TaintedSource{TxHash: h.TxHash, Vout: h.Vout, Addresses: h.Addresses}
+			e.reached[op] = s
+		}
+		s.Share += weight
+		return nil
+	}
+
+	if e.policy == TaintFIFO {
+		return e.propagateFIFO(h, weight)
+	}
+
+	// The sets are weighted equally if none passes on any share of its
+	// inputs value.
+	var total float64
+	for _, set := range h.Matched {
+		total += set.PathPercentOfInputs
+	}
+
+	for _, set := range h.Matched {
+		setWeight := weight / float64(len(h.Matched))
+		if total > 0 {
+			setWeight = weight * set.PathPercentOfInputs / total
+		}
+
+		var setSum float64
+		for _, input := range set.Inputs {
+			setSum += input.Amount
+		}
+
+		if setSum <= 0 {
+			continue
+		}
+
+		for _, input := range set.Inputs {
+			if err := e.propagate(input, setWeight*input.Amount/setSum); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// propagateFIFO funds the hub output with the share of each input value it
+// takes if the tx inputs fund the tx outputs in their tx order. The funding
+// sets only list the inputs, the tx inputs missing from all the sets are not
+// traced.
+func (e *taintEngine) propagateFIFO(h *Hub, weight float64) error {
+	if h.Amount <= 0 || len(h.Matched) == 0 {
+		return nil
+	}
+
+	tx, err := RetrieveTxData(e.client, h.TxHash)
+	if err != nil {
+		return err
+	}
+
+	shares := fifoShares(tx, h.Vout)
+
+	seen := make(map[outpoint]bool)
+	for _, set := range h.Matched {
+		for _, input := range set.Inputs {
+			op := outpoint{txHash: input.TxHash, vout: input.Vout}
+			if seen[op] {
+				continue
+			}
+			seen[op] = true
+
+			for vin, in := range tx.Inpoints {
+				if in.TxHash == op.txHash && in.OutputTxIndex == op.vout {
+					err = e.propagate(input, weight*shares[vin]/h.Amount)
+					if err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fifoShares returns the value each input, by its tx index, passes on to the
+// output at vout if the inputs fund the outputs in their tx order. The outputs
+// are funded in their tx order too thus each output takes the inputs value the
+// previous outputs left.
+func fifoShares(tx *rpcutils.Transaction, vout uint32) map[int]float64 {
+	var start, amount float64
+	for _, out := range tx.Outpoints {
+		switch {
+		case out.TxIndex < vout:
+			start += out.Value
+		case out.TxIndex == vout:
+			amount = out.Value
+		}
+	}
+
+	shares := make(map[int]float64)
+
+	var cursor float64
+	for vin, in := range tx.Inpoints {
+		low := math.Max(cursor, start)
+		high := math.Min(cursor+in.ValueIn, start+amount)
+		if high > low {
+			shares[vin] = high - low
+		}
+		cursor += in.ValueIn
+	}
+	return shares
+}
+
+// isTainted checks if the hub output is one of the tainted outpoints or pays
+// to one of the tainted addresses.
+func (e *taintEngine) isTainted(h *Hub) bool {
+	if e.outpoints[outpoint{txHash: h.TxHash, vout: h.Vout}] {
+		return true
+	}
+
+	for _, addr := range h.Addresses {
+		if e.addresses[addr] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOutpoint parses an outpoint formatted as "txhash:vout".
+func parseOutpoint(s string) (outpoint, error) {
+	i := strings.LastIndex(s, ":")
+	if i > 0 {
+		vout, err := strconv.ParseUint(s[i+1:], 10, 32)
+		if err == nil {
+			return outpoint{txHash: s[:i], vout: uint32(vout)}, nil
+		}
+	}
+
+	return outpoint{}, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+		fmt.Sprintf("invalid outpoint %q: expected txhash:vout", s))
+}
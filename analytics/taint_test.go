@@ -0,0 +1,192 @@
+package analytics
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+const (
+	taintRootHash = "7100000000000000000000000000000000000000000000000000000000000000"
+	taintAHash    = "7200000000000000000000000000000000000000000000000000000000000000"
+	taintBHash    = "7300000000000000000000000000000000000000000000000000000000000000"
+	taintCHash    = "7400000000000000000000000000000000000000000000000000000000000000"
+	taintDHash    = "7500000000000000000000000000000000000000000000000000000000000000"
+)
+
+// taintTestChain returns a chain whose root output of 4 DCR is funded by a
+// 2 DCR clean input spent first and a 3 DCR input paying to a tainted address.
+// The clean input is funded by either a tainted outpoint, passing on three
+// times the value share, or a clean output.
+func taintTestChain() *Hub {
+	return &Hub{
+		TxHash: taintRootHash,
+		Vout:   1,
+		Amount: 4,
+		Matched: []Set{{PathPercentOfInputs: 0.8, Inputs: []*Hub{
+			{TxHash: taintAHash, Vout: 1, Amount: 3, Addresses: []string{"TsBad"}},
+			{TxHash: taintBHash, Vout: 1, Amount: 2, Addresses: []string{"TsClean"},
+				Matched: []Set{
+					{PathPercentOfInputs: 0.3, Inputs: []*Hub{{TxHash: taintCHash, Amount: 2}}},
+					{PathPercentOfInputs: 0.1, Inputs: []*Hub{{TxHash: taintDHash, Amount: 2}}},
+				}},
+		}}},
+	}
+}
+
+// taintTestSource returns the txs of the taintTestChain hubs. The root output
+// follows a 1 DCR output thus it takes 1 DCR of the clean input and 3 DCR of
+// the tainted input in their tx order. The clean input also follows a 1 DCR
+// output and takes 1 DCR of each of its inputs, the clean output first.
+func taintTestSource() mapSource {
+	return mapSource{
+		taintRootHash: {
+			Txid: taintRootHash,
+			Vin: []dcrjson.Vin{{Txid: taintBHash, Vout: 1, AmountIn: 2},
+				{Txid: taintAHash, Vout: 1, AmountIn: 3}},
+			Vout: []dcrjson.Vout{{Value: 1, N: 0}, {Value: 4, N: 1}},
+		},
+		taintBHash: {
+			Txid: taintBHash,
+			Vin: []dcrjson.Vin{{Txid: taintDHash, Vout: 0, AmountIn: 2},
+				{Txid: taintCHash, Vout: 0, AmountIn: 2}},
+			Vout: []dcrjson.Vout{{Value: 1, N: 0}, {Value: 2, N: 1}, {Value: 0.99, N: 2}},
+		},
+	}
+}
+
+// TestComputeTaint tests the taint found by each taint policy.
+func TestComputeTaint(t *testing.T) {
+	type testData struct {
+		Policy   TaintPolicy
+		Expected *TaintResult
+	}
+
+	sourceA := func(share, amount float64) *TaintedSource {
+		return &TaintedSource{TxHash: taintAHash, Vout: 1, Addresses: []string{"TsBad"},
+			Share: share, Amount: amount}
+	}
+
+	sourceC := func(share, amount float64) *TaintedSource {
+		return &TaintedSource{TxHash: taintCHash, Share: share, Amount: amount}
+	}
+
+	td := []testData{
+		{
+			Policy: TaintHaircut,
+			Expected: &TaintResult{TxHash: taintRootHash, Vout: 1, Amount: 4, Policy: TaintHaircut,
+				Score: 0.9, TaintedAmount: 3.6,
+				Sources: []*TaintedSource{sourceA(0.6, 2.4), sourceC(0.3, 1.2)}},
+		},
+		{
+			Policy: TaintPoison,
+			Expected: &TaintResult{TxHash: taintRootHash, Vout: 1, Amount: 4, Policy: TaintPoison,
+				Score: 1, TaintedAmount: 4,
+				Sources: []*TaintedSource{sourceA(1, 4), sourceC(1, 4)}},
+		},
+		{
+			Policy: TaintFIFO,
+			Expected: &TaintResult{TxHash: taintRootHash, Vout: 1, Amount: 4, Policy: TaintFIFO,
+				Score: 0.875, TaintedAmount: 3.5,
+				Sources: []*TaintedSource{sourceA(0.75, 3), sourceC(0.125, 0.5)}},
+		},
+	}
+
+	sources := TaintSources{Outpoints: []string{taintCHash + ":0"},
+		Addresses: []string{"TsBad"}}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			result, err := ComputeTaint(taintTestSource(), taintTestChain(), sources,
+				data.Policy)
+			if err != nil {
+				t.Fatalf("expected no error but found: %v", err)
+			}
+
+			if !reflect.DeepEqual(result, data.Expected) {
+				t.Fatalf("expected taint %+v but found %+v", data.Expected, result)
+			}
+		})
+	}
+}
+
+// TestFIFOShares tests that the outputs take the inputs value the previous
+// outputs left in their tx order.
+func TestFIFOShares(t *testing.T) {
+	tx := &rpcutils.Transaction{
+		Inpoints: []rpcutils.TxInput{{ValueIn: 2}, {ValueIn: 3}, {ValueIn: 1}},
+		Outpoints: []rpcutils.TxOutput{{Value: 1, TxIndex: 0}, {Value: 3, TxIndex: 1},
+			{Value: 1.5, TxIndex: 2}},
+	}
+
+	td := []map[int]float64{{0: 1}, {0: 1, 1: 2}, {1: 1, 2: 0.5}}
+
+	for i, expected := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			shares := fifoShares(tx, uint32(i))
+			if !reflect.DeepEqual(shares, expected) {
+				t.Fatalf("expected the shares %v but found %v", expected, shares)
+			}
+		})
+	}
+}
+
+// TestTaintBehindCertainLink tests that the taint of a source sitting behind
+// a certain link is only found if the chain keeps following the certain links.
+func TestTaintBehindCertainLink(t *testing.T) {
+	client := labeledSource()
+	sources := TaintSources{Addresses: []string{"TsDeposit"}}
+
+	td := []struct {
+		Options ChainOptions
+		Score   float64
+	}{
+		{Options: ChainOptions{}, Score: 0},
+		{Options: ChainOptions{FollowCertain: true}, Score: 1},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			chain, _, err := ChainDiscoveryWithOptions(client, labeledTxHash, data.Options, 0)
+			if err != nil {
+				t.Fatalf("expected no error but found: %v", err)
+			}
+
+			result, err := ComputeTaint(client, chain[0], sources, TaintHaircut)
+			if err != nil {
+				t.Fatalf("expected no error but found: %v", err)
+			}
+
+			if result.Score != data.Score {
+				t.Fatalf("expected the score %v but found %v", data.Score, result.Score)
+			}
+		})
+	}
+}
+
+// TestComputeTaintErrors tests that the invalid taint requests are rejected.
+func TestComputeTaintErrors(t *testing.T) {
+	type testData struct {
+		Sources TaintSources
+		Policy  TaintPolicy
+	}
+
+	td := []testData{
+		{Sources: TaintSources{Addresses: []string{"TsBad"}}, Policy: "lifo"},
+		{Sources: TaintSources{}, Policy: TaintHaircut},
+		{Sources: TaintSources{Outpoints: []string{"c"}}, Policy: TaintHaircut},
+		{Sources: TaintSources{Outpoints: []string{"c:-1"}}, Policy: TaintHaircut},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			_, err := ComputeTaint(nil, taintTestChain(), data.Sources, data.Policy)
+			if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != rpcutils.ErrInvalidRequest {
+				t.Fatalf("expected a %v error but found %v", rpcutils.ErrInvalidRequest, err)
+			}
+		})
+	}
+}
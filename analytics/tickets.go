@@ -0,0 +1,140 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package analytics
+
+import (
+	"github.com/decred/dcrd/blockchain/stake"
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// vspFeeMaxShare is the largest share of the ticket commitments a VSP fee
+// commitment takes.
+const vspFeeMaxShare = 0.1
+
+// TicketParticipant is a ticket purchaser funding the ticket with an input.
+// The input at index Input is committed by the commitment output at index
+// Commitment and its change is returned by the change output at index Change.
+// Contribution is the input amount less the change and RewardShare the share
+// of the ticket rewards committed to the participant reward address.
+type TicketParticipant struct {
+	Input           int
+	InputAmount     float64
+	Commitment      int
+	RewardAddress   string `json:",omitempty"`
+	CommitAmount    float64
+	Change          int
+	ChangeAmount    float64
+	ChangeAddresses []string `json:",omitempty"`
+	Contribution    float64
+	RewardShare     float64
+	VSPFee          bool `json:",omitempty"`
+}
+
+// TicketAnalysis holds the decoded structure of a ticket. Price is the ticket
+// stake submission amount and VotingAddresses the addresses allowed to vote
+// with the ticket. A split ticket is funded by more than one participant, the
+// VSP fee participant aside.
+type TicketAnalysis struct {
+	Price           float64
+	VotingAddresses []string `json:",omitempty"`
+	Participants    []*TicketParticipant
+	Split           bool
+	VSPFee          float64 `json:",omitempty"`
+}
+
+// AnalyzeTicket maps each ticket input to its commitment and change outputs.
+// A ticket holds the stake submission output followed by a commitment and a
+// change output per input in the inputs order. The first participant of a
+// ticket funded by two participants is taken as the VSP fee if it is
+// committed at most a tenth of the rewards, the way the VSPs split their fee
+// from the voting tickets. nil is returned if the tx is not a ticket.
+func AnalyzeTicket(tx *rpcutils.Transaction) *TicketAnalysis {
+	if stake.TxType(tx.TxType) != stake.TxTypeSStx || len(tx.Inpoints) == 0 ||
+		len(tx.Outpoints) != 2*len(tx.Inpoints)+1 {
+		return nil
+	}
+
+	ticket := &TicketAnalysis{
+		Price:           tx.Outpoints[0].Value,
+		VotingAddresses: tx.Outpoints[0].PkScriptData.Addresses,
+		Participants:    make([]*TicketParticipant, 0, len(tx.Inpoints)),
+	}
+
+	var committed float64
+	for i, in := range tx.Inpoints {
+		commitment, change := tx.Outpoints[2*i+1], tx.Outpoints[2*i+2]
+
+		p := &TicketParticipant{
+			Input:           i,
+			InputAmount:     in.ValueIn,
+			Commitment:      2*i + 1,
+			CommitAmount:    commitment.PkScriptData.CommitAmount,
+			Change:          2*i + 2,
+			ChangeAmount:    change.Value,
+			ChangeAddresses: change.PkScriptData.Addresses,
+			Contribution:    roundOff(in.ValueIn - change.Value),
+		}
+
+		if addrs := commitment.PkScriptData.Addresses; len(addrs) > 0 {
+			p.RewardAddress = addrs[0]
+		}
+
+		committed += p.CommitAmount
+		ticket.Participants = append(ticket.Participants, p)
+	}
+
+	for _, p := range ticket.Participants {
+		if committed > 0 {
+			p.RewardShare = roundOff(p.CommitAmount / committed)
+		}
+	}
+
+	if fee := ticket.Participants[0]; len(ticket.Participants) == 2 &&
+		fee.RewardShare <= vspFeeMaxShare {
+		fee.VSPFee = true
+	}
+
+	ticket.update()
+	return ticket
+}
+
+// ApplyLabels flags the participants whose reward address is labeled as a VSP
+// as the VSP fee.
+func (t *TicketAnalysis) ApplyLabels(labeler Labeler) {
+	if t == nil || labeler == nil {
+		return
+	}
+
+	for _, p := range t.Participants {
+		if p.RewardAddress == "" {
+			continue
+		}
+
+		for _, l := range labeler.Lookup([]string{p.RewardAddress}) {
+			if l.Category == labels.CategoryVSP {
+				p.VSPFee = true
+			}
+		}
+	}
+
+	t.update()
+}
+
+// update sets the VSP fee total and whether the ticket is split from the
+// participants.
+func (t *TicketAnalysis) update() {
+	var purchasers int
+	t.VSPFee = 0
+
+	for _, p := range t.Participants {
+		if p.VSPFee {
+			t.VSPFee = roundOff(t.VSPFee + p.Contribution)
+		} else {
+			purchasers++
+		}
+	}
+
+	t.Split = purchasers > 1
+}
@@ -0,0 +1,164 @@
+package analytics
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/txscript"
+	"github.com/decred/dcrd/wire"
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
+	"github.com/raedahgroup/dcrchainanalysis/v1/networkconfig"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// ticketInput is a ticket purchaser input with its reward commitment and
+// change amounts.
+type ticketInput struct {
+	Amount float64
+	Commit float64
+	Change float64
+}
+
+// testAddress returns a testnet public key hash address set from the id.
+func testAddress(t *testing.T, id byte) dcrutil.Address {
+	hash := make([]byte, 20)
+	hash[0] = id
+
+	addr, err := dcrutil.NewAddressPubKeyHash(hash, networkconfig.TestNet.ChainParams(), 0)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+	return addr
+}
+
+// newTicket returns a ticket of the price funded by the inputs.
+func newTicket(t *testing.T, price float64, inputs ...ticketInput) *rpcutils.Transaction {
+	toAmount := func(v float64) dcrutil.Amount {
+		amount, _ := dcrutil.NewAmount(v)
+		return amount
+	}
+
+	script := func(s []byte, err error) []byte {
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+		return s
+	}
+
+	tx := wire.NewMsgTx()
+	tx.AddTxOut(wire.NewTxOut(int64(toAmount(price)),
+		script(txscript.PayToSStx(testAddress(t, 100)))))
+
+	amountsIn := make([]float64, 0, len(inputs))
+	for i, in := range inputs {
+		hash := chainhash.Hash{byte(i + 1)}
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&hash, 0, wire.TxTreeRegular),
+			int64(toAmount(in.Amount)), nil))
+		amountsIn = append(amountsIn, in.Amount)
+
+		reward := script(txscript.GenerateSStxAddrPush(testAddress(t, byte(2*i+1)),
+			toAmount(in.Commit), 0x5800))
+		change := script(txscript.PayToSStxChange(testAddress(t, byte(2*i+2))))
+
+		tx.AddTxOut(wire.NewTxOut(0, reward))
+		tx.AddTxOut(wire.NewTxOut(int64(toAmount(in.Change)), change))
+	}
+
+	return rpcutils.ExtractMsgTxTransaction(tx, amountsIn, networkconfig.TestNet)
+}
+
+// TestAnalyzeTicket tests that the ticket inputs are mapped to their
+// commitments and that the VSP fee and split tickets are found.
+func TestAnalyzeTicket(t *testing.T) {
+	type testData struct {
+		Tx       *rpcutils.Transaction
+		Labeler  Labeler
+		IsTicket bool
+		Split    bool
+		VSPFee   float64
+		Shares   []float64
+		Fees     []bool
+	}
+
+	// The VSP fee reward address is the first commitment address.
+	vspLabeler := mapLabeler{testAddress(t, 1).String(): &labels.Label{
+		Address: testAddress(t, 1).String(), Entity: "VSP A", Category: labels.CategoryVSP}}
+
+	td := []testData{
+		{
+			Tx: newTicket(t, 100, ticketInput{Amount: 5, Commit: 4, Change: 1},
+				ticketInput{Amount: 98, Commit: 96, Change: 2}),
+			IsTicket: true, VSPFee: 4, Shares: []float64{0.04, 0.96}, Fees: []bool{true, false},
+		},
+		{
+			Tx: newTicket(t, 100, ticketInput{Amount: 30, Commit: 30},
+				ticketInput{Amount: 40, Commit: 40}, ticketInput{Amount: 31, Commit: 30, Change: 1}),
+			IsTicket: true, Split: true, Shares: []float64{0.3, 0.4, 0.3},
+			Fees: []bool{false, false, false},
+		},
+		{
+			Tx: newTicket(t, 100, ticketInput{Amount: 30, Commit: 30},
+				ticketInput{Amount: 40, Commit: 40}, ticketInput{Amount: 31, Commit: 30, Change: 1}),
+			Labeler: vspLabeler, IsTicket: true, Split: true, VSPFee: 30,
+			Shares: []float64{0.3, 0.4, 0.3}, Fees: []bool{true, false, false},
+		},
+		{
+			Tx:       newTicket(t, 100, ticketInput{Amount: 101, Commit: 100, Change: 1}),
+			IsTicket: true, Shares: []float64{1}, Fees: []bool{false},
+		},
+		{
+			Tx: &rpcutils.Transaction{
+				Inpoints:  []rpcutils.TxInput{{ValueIn: 2}},
+				Outpoints: []rpcutils.TxOutput{{Value: 1}, {Value: 0.5}, {Value: 0.49}},
+			},
+		},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			ticket := AnalyzeTicket(data.Tx)
+			if !data.IsTicket {
+				if ticket != nil {
+					t.Fatalf("expected no ticket analysis but found %+v", ticket)
+				}
+				return
+			}
+
+			if ticket == nil {
+				t.Fatal("expected a ticket analysis but found none")
+			}
+
+			ticket.ApplyLabels(data.Labeler)
+
+			if ticket.Price != 100 || len(ticket.VotingAddresses) != 1 ||
+				ticket.VotingAddresses[0] != testAddress(t, 100).String() {
+				t.Fatalf("expected the ticket price 100 and its voting address but found %v and %v",
+					ticket.Price, ticket.VotingAddresses)
+			}
+
+			if ticket.Split != data.Split || ticket.VSPFee != data.VSPFee {
+				t.Fatalf("expected split %v and VSP fee %v but found %v and %v",
+					data.Split, data.VSPFee, ticket.Split, ticket.VSPFee)
+			}
+
+			if len(ticket.Participants) != len(data.Shares) {
+				t.Fatalf("expected %d participants but found %d", len(data.Shares),
+					len(ticket.Participants))
+			}
+
+			for j, p := range ticket.Participants {
+				if p.Input != j || p.Commitment != 2*j+1 || p.Change != 2*j+2 ||
+					p.RewardAddress != testAddress(t, byte(2*j+1)).String() {
+					t.Fatalf("expected input %d to map to its outputs but found %+v", j, p)
+				}
+
+				if p.RewardShare != data.Shares[j] || p.VSPFee != data.Fees[j] {
+					t.Fatalf("expected participant %d reward share %v and VSP fee %v but found %v and %v",
+						j, data.Shares[j], data.Fees[j], p.RewardShare, p.VSPFee)
+				}
+			}
+		})
+	}
+}
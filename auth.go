@@ -0,0 +1,275 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+package main
+
+import (
+	"crypto/subtle"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// apiKeyHeader is the request header that holds the API key.
+	apiKeyHeader = "X-API-Key"
+
+	// maxRateBuckets is the number of clients tracked by a rate limiter before
+	// the buckets of the idle clients are dropped.
+	maxRateBuckets = 10000
+)
+
+// publicRoutes are the routes served without authentication and rate limits.
+var publicRoutes = map[string]bool{
+	"/":         true,
+	"/metrics":  true,
+	"/api/v2":   true,
+	openAPIPath: true,
+}
+
+// expensiveRoutes are the routes that are also subject to the stricter
+// expensive routes rate limit.
+var expensiveRoutes = map[string]bool{
+	"/api/v1/common-ancestry":              true,
+	"/api/v1/{tx}/all":                     true,
+	"/api/v1/{tx}/chain":                   true,
+	"/api/v1/{tx}/chain/{index:[0-9]+}":    true,
+	"/api/v1/{tx}/taint/{index:[0-9]+}":    true,
+	"/api/v1/{tx}/path/{index:[0-9]+}":     true,
+	"/api/v2/tx/{tx}/solutions":            true,
+	"/api/v2/tx/{tx}/chain":                true,
+	"/api/v2/tx/{tx}/chain/{index:[0-9]+}": true,
+	"/api/v2/tx/{tx}/taint/{index:[0-9]+}": true,
+	"/api/v2/tx/{tx}/privacy":              true,
+	"/api/v2/tx/{tx}/path/{index:[0-9]+}":  true,
+}
+
+// adminRoutes are the routes that require an admin key. They are not subject
+// to the API keys and the rate limits and are disabled if no admin key is set.
+var adminRoutes = map[string]bool{
+	"/api/v1/admin/labels":           true,
+	"/api/v1/admin/labels/{address}": true,
+}
+
+// tokenBucket holds the tokens available to a single client.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a per client token bucket rate limiter. Each client bucket
+// holds up to burst tokens and is refilled at rate tokens per second.
+type rateLimiter struct {
+	mtx     sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+}
+
+// newRateLimiter returns a rateLimiter that allows rate requests per second
+// with bursts of up to burst requests. nil is returned if rate is not positive
+// which disables the rate limiting.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token from the client bucket. If the bucket is empty false is
+// returned together with the duration after which a token will be available.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxRateBuckets {
+			l.prune(now)
+		}
+
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// refund gives back the token taken from the client bucket by an allowed
+// request that was rejected afterwards.
+func (l *rateLimiter) refund(client string) {
+	if l == nil {
+		return
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if b, ok := l.buckets[client]; ok {
+		b.tokens = math.Min(l.burst, b.tokens+1)
+	}
+}
+
+// prune drops the buckets that have been refilled since they were last used.
+// Such clients start again with a full bucket so no state is lost.
+func (l *rateLimiter) prune(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// apiAuth authenticates the API requests and applies the per client rate
+// limits. The API keys identify the clients when they are set, otherwise the
+// clients are identified by their IP address. The admin keys grant access to
+// the admin routes only.
+type apiAuth struct {
+	keys      [][]byte
+	adminKeys [][]byte
+	limiter   *rateLimiter
+	expensive *rateLimiter
+}
+
+// newAPIAuth returns the apiAuth configured. nil is returned if no API keys, no
+// admin keys and no rate limits are set.
+func newAPIAuth(cfg *config) *apiAuth {
+	auth := &apiAuth{
+		limiter:   newRateLimiter(cfg.RateLimit, cfg.RateBurst),
+		expensive: newRateLimiter(cfg.ExpensiveRateLimit, cfg.ExpensiveRateBurst),
+	}
+
+	for _, key := range cfg.APIKeys {
+		auth.keys = append(auth.keys, []byte(key))
+	}
+
+	for _, key := range cfg.AdminKeys {
+		auth.adminKeys = append(auth.adminKeys, []byte(key))
+	}
+
+	if len(auth.keys) == 0 && len(auth.adminKeys) == 0 && auth.limiter == nil &&
+		auth.expensive == nil {
+		return nil
+	}
+	return auth
+}
+
+// validKey checks if the key provided is one of the keys configured.
+func validKey(keys [][]byte, key string) bool {
+	var valid int
+	for _, k := range keys {
+		valid |= subtle.ConstantTimeCompare(k, []byte(key))
+	}
+	return valid == 1
+}
+
+// middleware rejects the unauthenticated requests with 401 and the requests
+// over the client rate limits with 429. The admin routes requests without a
+// valid admin key are rejected with 401.
+func (a *apiAuth) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var route string
+		if current := mux.CurrentRoute(r); current != nil {
+			route, _ = current.GetPathTemplate()
+		}
+
+		t := time.Now()
+
+		if adminRoutes[route] {
+			if a == nil || !validKey(a.adminKeys, r.Header.Get(apiKeyHeader)) {
+				unauthorized("a valid admin key", t, w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if a == nil || publicRoutes[route] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client := r.Header.Get(apiKeyHeader)
+		if len(a.keys) > 0 {
+			if !validKey(a.keys, client) {
+				unauthorized("a valid API key", t, w)
+				return
+			}
+		} else {
+			client = clientIP(r)
+		}
+
+		allowed, wait := a.limiter.allow(client, t)
+		// The outputs privacy scores trace the outputs like the chain routes.
+		privacy, _ := strconv.ParseBool(r.URL.Query().Get("privacy"))
+		expensive := expensiveRoutes[route] || privacy
+		if allowed && expensive {
+			allowed, wait = a.expensive.allow(client, t)
+			if !allowed {
+				// A request rejected by the expensive limits does not use up
+				// the general budget.
+				a.limiter.refund(client)
+			}
+		}
+
+		if !allowed {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			errorWrite(errorResponse{
+				Error:    "rate limit exceeded, retry after " + strconv.Itoa(retryAfter) + "s",
+				Code:     "rate_limited",
+				Duration: durationInSec(t),
+			}, http.StatusTooManyRequests, w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unauthorized rejects the request with 401 stating the key required.
+func unauthorized(required string, t time.Time, w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", apiKeyHeader)
+	errorWrite(errorResponse{
+		Error:    required + " is required in the " + apiKeyHeader + " header",
+		Code:     "unauthorized",
+		Duration: durationInSec(t),
+	}, http.StatusUnauthorized, w)
+}
+
+// clientIP returns the IP address of the client that sent the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return strings.TrimSpace(r.RemoteAddr)
+	}
+	return host
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestRateLimiter tests that the token buckets allow bursts and are refilled
+// at the configured rate.
+func TestRateLimiter(t *testing.T) {
+	type testData struct {
+		Client  string
+		Elapsed time.Duration
+		Allowed bool
+	}
+
+	// 2 requests per second with bursts of up to 2 requests.
+	td := []testData{
+		{Client: "a", Allowed: true},
+		{Client: "a", Allowed: true},
+		{Client: "a", Allowed: false},
+		{Client: "b", Allowed: true},
+		{Client: "a", Elapsed: 250 * time.Millisecond, Allowed: false},
+		{Client: "a", Elapsed: 500 * time.Millisecond, Allowed: true},
+		{Client: "a", Elapsed: 500 * time.Millisecond, Allowed: false},
+		{Client: "a", Elapsed: 10 * time.Second, Allowed: true},
+		{Client: "a", Elapsed: 10 * time.Second, Allowed: true},
+		{Client: "a", Elapsed: 10 * time.Second, Allowed: false},
+	}
+
+	l := newRateLimiter(2, 2)
+	now := time.Now()
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			allowed, wait := l.allow(data.Client, now.Add(data.Elapsed))
+			if allowed != data.Allowed {
+				t.Fatalf("expected allowed to be %v but found %v", data.Allowed, allowed)
+			}
+
+			if !allowed && wait <= 0 {
+				t.Fatalf("expected a positive wait duration but found %v", wait)
+			}
+		})
+	}
+}
+
+// TestAPIAuth tests that the requests without a valid API key and the requests
+// over the rate limits are rejected.
+func TestAPIAuth(t *testing.T) {
+	type testData struct {
+		Path   string
+		Key    string
+		Status int
+	}
+
+	td := []testData{
+		{Path: "/metrics", Status: http.StatusOK},
+		{Path: "/api/v1/" + replayTxID, Status: http.StatusUnauthorized},
+		{Path: "/api/v1/" + replayTxID, Key: "invalid", Status: http.StatusUnauthorized},
+		{Path: "/api/v1/" + replayTxID, Key: "key-a", Status: http.StatusOK},
+		{Path: "/api/v1/" + replayTxID + "/all", Key: "key-a", Status: http.StatusOK},
+		// The expensive routes budget of key-a is used up, the rejected request
+		// does not use up the general budget.
+		{Path: "/api/v1/" + replayTxID + "/chain", Key: "key-a", Status: http.StatusTooManyRequests},
+		{Path: "/api/v1/" + replayTxID, Key: "key-a", Status: http.StatusOK},
+		{Path: "/api/v1/" + replayTxID, Key: "key-a", Status: http.StatusOK},
+		// The general budget of key-a is used up.
+		{Path: "/api/v1/" + replayTxID, Key: "key-a", Status: http.StatusTooManyRequests},
+		{Path: "/api/v1/" + replayTxID + "/chain", Key: "key-b", Status: http.StatusOK},
+		// Only the privacy scores requested are charged as expensive.
+		{Path: "/api/v1/" + replayTxID + "?privacy=false", Key: "key-b", Status: http.StatusOK},
+		{Path: "/api/v1/" + replayTxID + "?privacy=true", Key: "key-b",
+			Status: http.StatusTooManyRequests},
+	}
+
+	exp := newTestExplorer()
+	exp.Auth = newAPIAuth(&config{
+		APIKeys:            []string{"key-a", "key-b"},
+		RateLimit:          0.001,
+		RateBurst:          4,
+		ExpensiveRateLimit: 0.001,
+		ExpensiveRateBurst: 1,
+	})
+
+	router := newRouter(exp)
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			req := httptest.NewRequest("GET", data.Path, nil)
+			if data.Key != "" {
+				req.Header.Set(apiKeyHeader, data.Key)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != data.Status {
+				t.Fatalf("expected status %d but found %d: %s", data.Status, w.Code,
+					w.Body.String())
+			}
+
+			if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Fatal("expected the Retry-After header to be set but found none")
+			}
+		})
+	}
+}
@@ -0,0 +1,483 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/analytics"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+const (
+	// outputJSON prints the subcommands results as indented JSON.
+	outputJSON = "json"
+
+	// outputTree prints the subcommands results as human readable text. The
+	// chain funds flow paths are printed as a tree.
+	outputTree = "tree"
+
+	// outputExplain prints the tx, solutions and block subcommands results as
+	// plain language statements. The chain results are printed as a tree.
+	outputExplain = "explain"
+)
+
+// Subcommands exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage is returned when a subcommand is invoked with invalid arguments.
+var errUsage = errors.New("invalid arguments")
+
+// blockSolution defines the full structure of the funds flow analysis of all
+// the transactions in a block. Entropy aggregates the txs entropy metrics.
+type blockSolution struct {
+	TimeData
+	Height  int64
+	Hash    string
+	Entropy *analytics.EntropyStats
+	Data    []*analytics.TxAnalysis
+}
+
+// cliCommand defines a one-shot analysis subcommand.
+type cliCommand struct {
+	usage   string
+	minArgs int
+	maxArgs int
+	run     func(exp *explorer, args []string) (interface{}, error)
+}
+
+// cliCommands lists all the subcommands supported.
+var cliCommands = map[string]cliCommand{
+	"tx": {
+		usage:   "tx <hash>\tPrints the funds flow probability of the tx outputs",
+		minArgs: 1, maxArgs: 1,
+		run: (*explorer).txCommand,
+	},
+	"solutions": {
+		usage:   "solutions <hash>\tPrints all the raw funds flow solutions of the tx",
+		minArgs: 1, maxArgs: 1,
+		run: (*explorer).solutionsCommand,
+	},
+	"chain": {
+		usage:   "chain <hash> [index]\tPrints the funds flow paths of the tx output(s)",
+		minArgs: 1, maxArgs: 2,
+		run: (*explorer).chainCommand,
+	},
+	"block": {
+		usage:   "block <height>\tPrints the funds flow probability of all the block txs",
+		minArgs: 1, maxArgs: 1,
+		run: (*explorer).blockCommand,
+	},
+}
+
+// runCommand executes the subcommand in args writing its result to stdout and
+// the usage or error messages to stderr. It returns the process exit code:
+// exitUsage on an unknown subcommand or invalid arguments and exitError if the
+// subcommand failed.
+func runCommand(exp *explorer, args []string, stdout, stderr io.Writer) int {
+	cmd, ok := cliCommands[args[0]]
+	if !ok || len(args)-1 < cmd.minArgs || len(args)-1 > cmd.maxArgs {
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	result, err := cmd.run(exp, args[1:])
+	if err == errUsage {
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "%s failed: %v\n", args[0], err)
+		return exitError
+	}
+
+	switch exp.Params.Output {
+	case outputTree:
+		err = writeText(stdout, result)
+	case outputExplain:
+		err = writeExplanation(stdout, result)
+	default:
+		err = writeJSON(stdout, result)
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "printing the %s result failed: %v\n", args[0], err)
+		return exitError
+	}
+	return exitOK
+}
+
+// printUsage writes the subcommands usage message.
+func printUsage(w io.Writer) {
+	names := make([]string, 0, len(cliCommands))
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: dcrchainanalysis [options] <command> [arguments]")
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", cliCommands[name].usage)
+	}
+}
+
+// txCommand returns the funds flow probability solution of the tx.
+func (exp *explorer) txCommand(args []string) (interface{}, error) {
+	t := time.Now()
+
+	analysis, txData, err := analytics.RetrieveTxAnalysis(exp.Client, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if err = exp.labelTx(analysis, txData); err != nil {
+		return nil, err
+	}
+
+	return probabilitySolution{
+		Fee:                analysis.Fee,
+		Data:               analysis.Probabilities,
+		DeterministicLinks: analysis.DeterministicLinks,
+		LinkMatrix:         analysis.LinkMatrix,
+		Entropy:            analysis.Entropy,
+		Ticket:             analysis.Ticket,
+		Labels:             analysis.Labels,
+		TimeData:           TimeData{TxTime: txData.BlockTime, Duration: durationInSec(t)},
+	}, nil
+}
+
+// solutionsCommand returns all the raw solutions of the tx.
+func (exp *explorer) solutionsCommand(args []string) (interface{}, error) {
+	t := time.Now()
+
+	analysis, txData, err := analytics.RetrieveTxAnalysis(exp.Client, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return rawSolution{
+		Data:     analysis.Solutions,
+		Total:    len(analysis.Solutions),
+		Entropy:  analysis.Entropy,
+		TimeData: TimeData{TxTime: txData.BlockTime, Duration: durationInSec(t)},
+	}, nil
+}
+
+// chainCommand returns the funds flow paths of all the tx outputs or of the
+// output at the index provided.
+func (exp *explorer) chainCommand(args []string) (interface{}, error) {
+	t := time.Now()
+
+	var outputIndex []int
+	if len(args) > 1 {
+		index, err := strconv.Atoi(args[1])
+		if err != nil || index < 0 {
+			return nil, errUsage
+		}
+		outputIndex = append(outputIndex, index)
+	}
+
+	opts := analytics.ChainOptions{Labels: exp.labeler(), Privacy: true}
+	chain, txTime, err := analytics.ChainDiscoveryWithOptions(exp.Client, args[0], opts,
+		outputIndex...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pathSolution{
+		Data:     chain,
+		Summary:  analytics.ChainSources(chain),
+		TimeData: TimeData{TxTime: txTime, Duration: durationInSec(t)},
+	}, nil
+}
+
+// blockCommand returns the funds flow analysis of all the txs in the block at
+// the provided height.
+func (exp *explorer) blockCommand(args []string) (interface{}, error) {
+	t := time.Now()
+
+	height, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || height < 0 {
+		return nil, errUsage
+	}
+
+	if exp.Nodes == nil {
+		return nil, fmt.Errorf("the block command needs the %s txsource", txSourceDcrd)
+	}
+
+	block, hash, err := rpcutils.GetBlock(exp.Nodes, height)
+	if err != nil {
+		return nil, err
+	}
+
+	analyses, err := analytics.AnalyzeBlock(block.MsgBlock(), exp.OtherParams.ActiveNet)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockSolution{
+		Height:  height,
+		Hash:    hash.String(),
+		Entropy: analytics.AggregateEntropy(analyses),
+		Data:    analyses,
+		TimeData: TimeData{
+			TxTime: block.MsgBlock().Header.Timestamp.Unix(), Duration: durationInSec(t),
+		},
+	}, nil
+}
+
+// writeJSON writes the result as indented JSON.
+func writeJSON(w io.Writer, result interface{}) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// writeText writes the result in a human readable format.
+func writeText(w io.Writer, result interface{}) error {
+	switch res := result.(type) {
+	case probabilitySolution:
+		writeProbabilities(w, res.Data, "")
+		writeDeterministicLinks(w, res.DeterministicLinks)
+		writeEntropy(w, res.Entropy, "")
+		writeLinkMatrix(w, res.LinkMatrix)
+
+	case rawSolution:
+		writeSolutions(w, res.Data)
+		writeEntropy(w, res.Entropy, "")
+
+	case pathSolution:
+		for _, hub := range res.Data {
+			writeHub(w, hub, "", "")
+			writePrivacy(w, hub.Privacy)
+		}
+		writeSources(w, res.Summary)
+
+	case blockSolution:
+		fmt.Fprintf(w, "Block %d (%s): %d transaction(s)\n", res.Height, res.Hash,
+			len(res.Data))
+		if e := res.Entropy; e != nil {
+			fmt.Fprintf(w, "Entropy: mean %v bits, max %v bits, mean density %v, "+
+				"%d of %d tx(s) deterministic\n", e.MeanEntropy, e.MaxEntropy,
+				e.MeanDensity, e.Deterministic, e.Txs)
+		}
+
+		for _, analysis := range res.Data {
+			fmt.Fprintf(w, "\nTx %s\n", analysis.TxID)
+			writeProbabilities(w, analysis.Probabilities, "  ")
+			writeEntropy(w, analysis.Entropy, "  ")
+		}
+
+	default:
+		return writeJSON(w, result)
+	}
+
+	return nil
+}
+
+// writeExplanation writes the result as plain language statements. The results
+// that cannot be explained are written as text.
+func writeExplanation(w io.Writer, result interface{}) error {
+	var statements []string
+
+	switch res := result.(type) {
+	case probabilitySolution:
+		statements = analytics.ExplainProbabilities(res.Data)
+
+	case rawSolution:
+		statements = analytics.ExplainSolutions(res.Data, res.Total)
+
+	case blockSolution:
+		fmt.Fprintf(w, "Block %d (%s): %d transaction(s)\n", res.Height, res.Hash,
+			len(res.Data))
+		for _, analysis := range res.Data {
+			fmt.Fprintf(w, "\nTx %s\n", analysis.TxID)
+			for _, s := range analytics.ExplainAnalysis(analysis) {
+				fmt.Fprintf(w, "  %s\n", s)
+			}
+		}
+		return nil
+
+	default:
+		return writeText(w, result)
+	}
+
+	for _, s := range statements {
+		fmt.Fprintln(w, s)
+	}
+	return nil
+}
+
+// writePrivacy writes the output privacy score and the factors that lowered it.
+func writePrivacy(w io.Writer, p *analytics.PrivacyScore) {
+	if p == nil {
+		return
+	}
+
+	fmt.Fprintf(w, "Privacy score of output %d: %v\n", p.Vout, p.Score)
+	for _, f := range p.Factors {
+		fmt.Fprintf(w, "  -%v %s\n", f.Penalty, f.Reason)
+	}
+}
+
+// writeSources writes the chains sources summary by address.
+func writeSources(w io.Writer, sources []*analytics.SourceSummary) {
+	if len(sources) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Sources:")
+	for _, s := range sources {
+		fmt.Fprintf(w, "  %s: %v DCR from %d output(s) (path %v, depth %v)\n",
+			strings.Join(s.Addresses, ", "), s.Amount, s.Sources, s.PathProbability, s.Depth)
+	}
+}
+
+// writeProbabilities writes the funds flow probability of each output.
+func writeProbabilities(w io.Writer, data []*analytics.FlowProbability, indent string) {
+	for _, p := range data {
+		if p.StatusMsg != "" {
+			fmt.Fprintf(w, "%s%s\n", indent, p.StatusMsg)
+			continue
+		}
+
+		fmt.Fprintf(w, "%sOutput %v DCR (x%d): linking probability %v\n", indent,
+			p.OutputAmount, p.Count, p.LinkingProbability)
+
+		for _, set := range p.ProbableInputs {
+			amounts := make([]string, len(set.Set))
+			for i, d := range set.Set {
+				amounts[i] = strconv.FormatFloat(d.Amount, 'f', -1, 64)
+			}
+
+			fmt.Fprintf(w, "%s  <- [%s] DCR (%v of inputs)\n", indent,
+				strings.Join(amounts, ", "), set.PercentOfInputs)
+		}
+	}
+}
+
+// writeDeterministicLinks writes the links found in all the tx solutions.
+func writeDeterministicLinks(w io.Writer, links []*analytics.DeterministicLink) {
+	if len(links) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Deterministic links:")
+	for _, l := range links {
+		var prefab string
+		if l.Prefabricated {
+			prefab = " (exact amount match)"
+		}
+		fmt.Fprintf(w, "  %v DCR -> %v DCR%s\n", l.InputAmount, l.OutputAmount, prefab)
+	}
+}
+
+// writeEntropy writes the tx entropy metrics.
+func writeEntropy(w io.Writer, e *analytics.TxEntropy, indent string) {
+	if e == nil {
+		return
+	}
+
+	deterministic := "not deterministic"
+	if e.Deterministic {
+		deterministic = "deterministic"
+	}
+
+	fmt.Fprintf(w, "%sEntropy: %v bits from %d interpretation(s), density %v, %s\n",
+		indent, e.Entropy, e.Interpretations, e.Density, deterministic)
+}
+
+// writeLinkMatrix writes the probability of each input funding each output as
+// a table with a row per input and a column per output.
+func writeLinkMatrix(w io.Writer, m *analytics.LinkMatrix) {
+	if m == nil {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "\nLinks (%d solution(s))\t", m.Solutions)
+	for _, out := range m.Outputs {
+		fmt.Fprintf(tw, "%v\t", out)
+	}
+	fmt.Fprintln(tw)
+
+	for i, in := range m.Inputs {
+		fmt.Fprintf(tw, "%v\t", in)
+		for _, p := range m.Probabilities[i] {
+			fmt.Fprintf(tw, "%v\t", p)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+}
+
+// writeSolutions writes the raw solutions with their funds flow buckets.
+func writeSolutions(w io.Writer, data []*analytics.AllFundsFlows) {
+	for _, sol := range data {
+		if sol.StatusMsg != "" {
+			fmt.Fprintln(w, sol.StatusMsg)
+			continue
+		}
+
+		fmt.Fprintf(w, "Solution %d (total fees %v DCR)\n", sol.Solution, sol.TotalFees)
+		for _, bucket := range sol.FundsFlow {
+			fmt.Fprintf(w, "  %v -> %v (fee %v)\n", bucket.Inputs.Values,
+				bucket.MatchedOutputs.Values, bucket.Fee)
+		}
+	}
+}
+
+// writeHub writes the hub and all its matched inputs as a tree.
+func writeHub(w io.Writer, hub *analytics.Hub, prefix, childPrefix string) {
+	if hub.TxHash == "" {
+		fmt.Fprintf(w, "%scoinbase/stakebase %v DCR", prefix, hub.Amount)
+	} else {
+		fmt.Fprintf(w, "%s%s:%d %v DCR", prefix, hub.TxHash, hub.Vout, hub.Amount)
+	}
+
+	if hub.LevelProbability > 0 {
+		fmt.Fprintf(w, " (level %v, path %v)", hub.LevelProbability, hub.PathProbability)
+	}
+
+	if hub.StatusMsg != "" {
+		fmt.Fprintf(w, " [%s]", hub.StatusMsg)
+	}
+	fmt.Fprintln(w)
+
+	for i, set := range hub.Matched {
+		branch, next := "├── ", "│   "
+		if i == len(hub.Matched)-1 {
+			branch, next = "└── ", "    "
+		}
+
+		fmt.Fprintf(w, "%s%sset %d (%v of inputs, path %v)\n", childPrefix, branch,
+			i+1, set.LevelPercentOfInputs, set.PathPercentOfInputs)
+
+		for k, input := range set.Inputs {
+			inBranch, inNext := "├── ", "│   "
+			if k == len(set.Inputs)-1 {
+				inBranch, inNext = "└── ", "    "
+			}
+
+			writeHub(w, input, childPrefix+next+inBranch, childPrefix+next+inNext)
+		}
+	}
+}
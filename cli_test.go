@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunCommand runs the subcommands against the recorded transactions and
+// compares their exit codes and outputs with the golden files.
+func TestRunCommand(t *testing.T) {
+	type testData struct {
+		Name   string
+		Args   []string
+		Output string
+		Code   int
+		Stderr string
+	}
+
+	td := []testData{
+		{Name: "tx", Args: []string{"tx", replayTxID}, Code: exitOK},
+		{Name: "solutions", Args: []string{"solutions", replayTxID}, Code: exitOK},
+		{Name: "chain", Args: []string{"chain", replayTxID, "3"}, Code: exitOK},
+		{Name: "chain_tree", Args: []string{"chain", replayTxID, "0"}, Output: outputTree,
+			Code: exitOK},
+		{Name: "tx_tree", Args: []string{"tx", replayTxID}, Output: outputTree, Code: exitOK},
+		{Args: []string{"unknown"}, Code: exitUsage, Stderr: "Usage:"},
+		{Args: []string{"tx"}, Code: exitUsage, Stderr: "Usage:"},
+		{Args: []string{"tx", replayTxID, "extra"}, Code: exitUsage, Stderr: "Usage:"},
+		{Args: []string{"chain", replayTxID, "x"}, Code: exitUsage, Stderr: "Usage:"},
+		{Args: []string{"chain", replayTxID, "10"}, Code: exitError, Stderr: "chain failed:"},
+		{Args: []string{"solutions", "not-a-hash"}, Code: exitError,
+			Stderr: "solutions failed:"},
+		{Code: exitError, Stderr: "tx failed:", Args: []string{"tx",
+			"ae40333aed99c0b004ef01834444944c471bc72ce869949f6f8ced728941f561"}},
+	}
+
+	for _, data := range td {
+		t.Run(strings.Join(data.Args, "_"), func(t *testing.T) {
+			exp := newTestExplorer()
+			exp.Params.Output = data.Output
+
+			var stdout, stderr bytes.Buffer
+			code := runCommand(exp, data.Args, &stdout, &stderr)
+			if code != data.Code {
+				t.Fatalf("expected the exit code %d but found %d: %s", data.Code, code,
+					stderr.String())
+			}
+
+			if !strings.Contains(stderr.String(), data.Stderr) {
+				t.Fatalf("expected %q in stderr but found %q", data.Stderr, stderr.String())
+			}
+
+			if data.Name == "" {
+				if stdout.Len() != 0 {
+					t.Fatalf("expected no result but found %s", stdout.String())
+				}
+				return
+			}
+
+			result := bytes.TrimSpace(stdout.Bytes())
+			if data.Output == "" {
+				result = withoutDuration(t, result)
+			}
+
+			matchGolden(t, filepath.Join("testdata", "cli", data.Name+".golden"), result)
+		})
+	}
+}
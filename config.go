@@ -12,23 +12,56 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/btcsuite/btclog"
 	"github.com/decred/dcrd/dcrutil"
 	"github.com/decred/dcrwallet/version"
 	flags "github.com/jessevdk/go-flags"
+	"github.com/raedahgroup/dcrchainanalysis/v1/analytics"
 	"github.com/raedahgroup/dcrchainanalysis/v1/networkconfig"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
 )
 
 const (
 	defaultLogLevel       = "info"
 	defaultLogDirname     = "logs"
 	defaultDataDirname    = "data"
+	defaultLabelsDirname  = "labels"
 	defaultDcrdHost       = "127.0.0.1"
 	defaultDCAHost        = "127.0.0.1" // dcrchainanalysis tool default host
 	defaultDCAPort        = "8476"      // dcrchainanalysis tool default port
+	defaultTxSource       = txSourceDcrd
 	defaultConfigFilename = "dcrchainanalyser.conf"
 	defaultLogFilename    = "dcrchainanalyser.log"
+
+	defaultTLSCertFilename = "rpc.cert"
+	defaultTLSKeyFilename  = "rpc.key"
+
+	// defaultAnalysisTimeout is less than the server write timeout so that the
+	// timeout error can still be written to the client.
+	defaultAnalysisTimeout = 25 * time.Second
+	defaultMaxAnalyses     = 8
+
+	defaultRateLimit          = 10
+	defaultRateBurst          = 20
+	defaultExpensiveRateLimit = 0.2
+	defaultExpensiveRateBurst = 2
+
+	defaultFeeTolerance = 0.1
+
+	defaultWatchProbability     = 0.9
+	defaultWebhookRetries       = 3
+	defaultWebhookRetryInterval = 5 * time.Second
+	defaultAlertsFilename       = "alerts.json"
+)
+
+const (
+	// txSourceDcrd sets the dcrd RPC backend(s) as the transactions data source.
+	txSourceDcrd = "dcrd"
+
+	// txSourceDcrdata sets the dcrdata REST API as the transactions data source.
+	txSourceDcrdata = "dcrdata"
 )
 
 var (
@@ -36,6 +69,10 @@ var (
 	defaultConfigFile        = filepath.Join(defaultAppDataDir, defaultConfigFilename)
 	defaultLogDir            = filepath.Join(defaultAppDataDir, defaultLogDirname)
 	defaultDataDir           = filepath.Join(defaultAppDataDir, defaultDataDirname)
+	defaultLabelsDir         = filepath.Join(defaultAppDataDir, defaultLabelsDirname)
+	defaultAlertsFile        = filepath.Join(defaultDataDir, defaultAlertsFilename)
+	defaultTLSCertFile       = filepath.Join(defaultAppDataDir, defaultTLSCertFilename)
+	defaultTLSKeyFile        = filepath.Join(defaultAppDataDir, defaultTLSKeyFilename)
 	dcrdHomeDir              = dcrutil.AppDataDir("dcrd", false)
 	defaultDaemonRPCCertFile = filepath.Join(dcrdHomeDir, "rpc.cert")
 )
@@ -50,10 +87,40 @@ type config struct {
 	TestNet     bool   `long:"testnet" description:"Use the test network (default mainnet)"`
 	SimNet      bool   `long:"simnet" description:"Use the simulation test network (default mainnet)"`
 	CPUProfile  bool   `long:"cpuprofile" description:"Use to profile this golang app"`
+	Output      string `long:"output" description:"Output format of the tx, solutions, chain and block subcommands {json, tree, explain}"`
+
+	// Funds flow analysis options
+	FeeMode      string  `long:"feemode" description:"How the tx fee is expected to be split between the funds flow buckets {any, single, proportional} (default any)"`
+	FeeTolerance float64 `long:"feetolerance" description:"Fraction of the tx fee a bucket fee may deviate from its share of the fee in the proportional fee mode (default 0.1)"`
 
 	// DCA server configuration
-	DCAHost string `long:"dcahost" description:"Chain analysis tool server host (default localhost)"`
-	DCAPort string `long:"dcaport" description:"Chain analysis tool server host (default 8476)"`
+	DCAHost         string        `long:"dcahost" description:"Chain analysis tool server host (default localhost)"`
+	DCAPort         string        `long:"dcaport" description:"Chain analysis tool server host (default 8476)"`
+	AnalysisTimeout time.Duration `long:"analysistimeout" description:"Maximum duration of an API analysis before a timeout error is returned, 0 disables it (default 25s)"`
+	MaxAnalyses     int           `long:"maxanalyses" description:"Maximum number of API analyses running at the same time, the timed out analyses included until they complete, 0 disables it (default 8)"`
+	TLSCert         string        `long:"tlscert" description:"File containing the server TLS certificate. A self-signed certificate is generated if neither tlscert nor tlskey exist"`
+	TLSKey          string        `long:"tlskey" description:"File containing the server TLS key"`
+	NoTLS           bool          `long:"notls" description:"Disable TLS for the server -- NOTE: This is only allowed if the server is listening on localhost"`
+
+	// API access options
+	APIKeys            []string `long:"apikey" description:"API key allowed to access the API in the X-API-Key header. Can be specified multiple times. The API is open if no key is set"`
+	RateLimit          float64  `long:"ratelimit" description:"Requests per second allowed per API key, or per IP address if no key is set. 0 disables it (default 10)"`
+	RateBurst          int      `long:"rateburst" description:"Maximum burst of requests allowed per API key (default 20)"`
+	ExpensiveRateLimit float64  `long:"expensiveratelimit" description:"Requests per second allowed per API key on the /all, /chain and /taint routes. 0 disables it (default 0.2)"`
+	ExpensiveRateBurst int      `long:"expensiverateburst" description:"Maximum burst of requests allowed per API key on the /all, /chain and /taint routes (default 2)"`
+	AdminKeys          []string `long:"adminkey" description:"Admin key allowed to access the /api/v1/admin routes in the X-API-Key header. Can be specified multiple times. The admin routes are disabled if no key is set"`
+
+	// Entity labels options
+	LabelsDir string `long:"labelsdir" description:"Directory with the CSV and JSON files of the addresses entity labels (default {appdata}/labels)"`
+
+	// Watchlist options
+	WatchAddresses       []string      `long:"watchaddress" description:"Address whose funds are followed through the new blocks. Can be specified multiple times"`
+	WatchOutpoints       []string      `long:"watchoutpoint" description:"Outpoint, as txhash:vout, whose funds are followed through the new blocks. Can be specified multiple times"`
+	WatchProbability     float64       `long:"watchprobability" description:"Least link probability from watched funds to a new output that raises an alert (default 0.9)"`
+	AlertsFile           string        `long:"alertsfile" description:"File the watchlist alerts are saved to (default {appdata}/data/alerts.json)"`
+	Webhooks             []string      `long:"webhook" description:"URL the watchlist alerts are posted to as JSON. Can be specified multiple times"`
+	WebhookRetries       int           `long:"webhookretries" description:"Number of times a failed webhook delivery is retried (default 3)"`
+	WebhookRetryInterval time.Duration `long:"webhookretryinterval" description:"Wait before the first webhook delivery retry, doubled on each retry (default 5s)"`
 
 	// RPC client options
 	DcrdUser         string `long:"dcrduser" description:"Daemon RPC user name"`
@@ -61,6 +128,17 @@ type config struct {
 	DcrdServ         string `long:"dcrdserv" description:"Hostname/IP and port of dcrd RPC server to connect to (default localhost:9109, testnet: localhost:19109, simnet: localhost:19556)"`
 	DcrdCert         string `long:"dcrdcert" description:"File containing the dcrd certificate file"`
 	DisableDaemonTLS bool   `long:"nodaemontls" description:"Disable TLS for the daemon RPC client -- NOTE: This is only allowed if the RPC client is connecting to localhost"`
+
+	// Transactions data source options
+	TxSource   string `long:"txsource" description:"Transactions data source {dcrd, dcrdata} (default dcrd)"`
+	DcrdataURL string `long:"dcrdataurl" description:"Base URL of the dcrdata instance used when txsource=dcrdata e.g. https://explorer.dcrdata.org"`
+
+	// Record and replay options
+	RecordDir string `long:"recorddir" description:"Directory to save every transaction fetched from the transactions source into as a fixture file"`
+	ReplayDir string `long:"replaydir" description:"Directory with recorded transaction fixtures to serve the transactions from instead of a live source"`
+
+	// Multiple dcrd backends options
+	DcrdBackends []string `long:"dcrdbackend" description:"Extra dcrd RPC backend in the form host:port[,user,pass,cert]. Omitted fields default to dcrduser, dcrdpass and dcrdcert. Can be specified multiple times"`
 }
 
 // extraParams defines the extra parameters that could not be added to the
@@ -68,6 +146,12 @@ type config struct {
 type extraParams struct {
 	RemainingArgs []string
 	ActiveNet     networkconfig.NetworkType
+	Backends      []rpcutils.NodeConfig
+}
+
+// watchEnabled checks if any address or outpoint is watched.
+func (cfg *config) watchEnabled() bool {
+	return len(cfg.WatchAddresses) > 0 || len(cfg.WatchOutpoints) > 0
 }
 
 // cleanAndExpandPath expands environement variables and leading ~ in the
@@ -117,6 +201,61 @@ func cleanAndExpandPath(path string) string {
 	return filepath.Join(homeDir, path)
 }
 
+// parseBackends returns the connection details of all the dcrd backends
+// configured. dcrdserv is used as the first backend if it was set or if no
+// other backend was configured.
+func parseBackends(cfg *config, activeNet networkconfig.NetworkType) (
+	[]rpcutils.NodeConfig, error) {
+	var backends []rpcutils.NodeConfig
+
+	if cfg.DcrdServ != "" || len(cfg.DcrdBackends) == 0 {
+		host := cfg.DcrdServ
+		if host == "" {
+			host = defaultDcrdHost + ":" + activeNet.RPCPort()
+		}
+
+		backends = append(backends, rpcutils.NodeConfig{
+			Host:       host,
+			User:       cfg.DcrdUser,
+			Pass:       cfg.DcrdPass,
+			Cert:       cfg.DcrdCert,
+			DisableTLS: cfg.DisableDaemonTLS,
+		})
+	}
+
+	for _, entry := range cfg.DcrdBackends {
+		fields := strings.Split(entry, ",")
+		if len(fields) > 4 || strings.TrimSpace(fields[0]) == "" {
+			return nil, fmt.Errorf("invalid dcrd backend entry %q: expected "+
+				"host:port[,user,pass,cert]", entry)
+		}
+
+		backend := rpcutils.NodeConfig{
+			Host:       strings.TrimSpace(fields[0]),
+			User:       cfg.DcrdUser,
+			Pass:       cfg.DcrdPass,
+			Cert:       cfg.DcrdCert,
+			DisableTLS: cfg.DisableDaemonTLS,
+		}
+
+		if len(fields) > 1 && fields[1] != "" {
+			backend.User = fields[1]
+		}
+
+		if len(fields) > 2 && fields[2] != "" {
+			backend.Pass = fields[2]
+		}
+
+		if len(fields) > 3 && fields[3] != "" {
+			backend.Cert = cleanAndExpandPath(fields[3])
+		}
+
+		backends = append(backends, backend)
+	}
+
+	return backends, nil
+}
+
 // validLogLevel returns whether or not logLevel is a valid debug log level.
 func validLogLevel(logLevel string) bool {
 	_, ok := btclog.LevelFromString(logLevel)
@@ -206,6 +345,26 @@ func loadConfig() (*config, *extraParams, error) {
 		AppDataDir: defaultAppDataDir,
 		LogDir:     defaultLogDir,
 		DcrdCert:   defaultDaemonRPCCertFile,
+		LabelsDir:  defaultLabelsDir,
+		AlertsFile: defaultAlertsFile,
+		TxSource:   defaultTxSource,
+		Output:     outputJSON,
+
+		FeeMode:      string(analytics.FeeModeAny),
+		FeeTolerance: defaultFeeTolerance,
+
+		TLSCert:            defaultTLSCertFile,
+		TLSKey:             defaultTLSKeyFile,
+		AnalysisTimeout:    defaultAnalysisTimeout,
+		MaxAnalyses:        defaultMaxAnalyses,
+		RateLimit:          defaultRateLimit,
+		RateBurst:          defaultRateBurst,
+		ExpensiveRateLimit: defaultExpensiveRateLimit,
+		ExpensiveRateBurst: defaultExpensiveRateBurst,
+
+		WatchProbability:     defaultWatchProbability,
+		WebhookRetries:       defaultWebhookRetries,
+		WebhookRetryInterval: defaultWebhookRetryInterval,
 	}
 
 	// Pre-parse the command line options to see if an alternative config
@@ -262,6 +421,19 @@ func loadConfig() (*config, *extraParams, error) {
 		RemainingArgs: remainingArgs,
 	}
 
+	if cfg.Output != outputJSON && cfg.Output != outputTree && cfg.Output != outputExplain {
+		err = fmt.Errorf("invalid output format %q: expected %s, %s or %s",
+			cfg.Output, outputJSON, outputTree, outputExplain)
+		fmt.Fprintln(os.Stderr, err)
+		return loadConfigError(err)
+	}
+
+	// Subcommands print their results to stdout so the logs are written to
+	// stderr instead.
+	if len(remainingArgs) > 0 {
+		logStdout = os.Stderr
+	}
+
 	// Fetch the active network configured.
 	switch {
 	case cfg.TestNet && cfg.SimNet:
@@ -275,14 +447,80 @@ func loadConfig() (*config, *extraParams, error) {
 		params.ActiveNet = networkconfig.MainNet
 	}
 
-	if cfg.DcrdServ == "" {
-		cfg.DcrdServ = defaultDcrdHost + ":" + params.ActiveNet.RPCPort()
+	switch cfg.TxSource {
+	case txSourceDcrd:
+	case txSourceDcrdata:
+		if cfg.DcrdataURL == "" {
+			err = fmt.Errorf("dcrdataurl must be set when txsource is %s",
+				txSourceDcrdata)
+			fmt.Fprintln(os.Stderr, err)
+			return loadConfigError(err)
+		}
+	default:
+		err = fmt.Errorf("invalid txsource %q: expected %s or %s",
+			cfg.TxSource, txSourceDcrd, txSourceDcrdata)
+		fmt.Fprintln(os.Stderr, err)
+		return loadConfigError(err)
+	}
+
+	if cfg.RecordDir != "" && cfg.ReplayDir != "" {
+		err = fmt.Errorf("recorddir and replaydir should not be set simultaneously")
+		fmt.Fprintln(os.Stderr, err)
+		return loadConfigError(err)
+	}
+
+	if cfg.RecordDir != "" {
+		cfg.RecordDir = cleanAndExpandPath(cfg.RecordDir)
+	}
+
+	if cfg.ReplayDir != "" {
+		cfg.ReplayDir = cleanAndExpandPath(cfg.ReplayDir)
+	}
+
+	params.Backends, err = parseBackends(&cfg, params.ActiveNet)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return loadConfigError(err)
+	}
+
+	if cfg.RateLimit < 0 || cfg.ExpensiveRateLimit < 0 {
+		err = fmt.Errorf("ratelimit and expensiveratelimit should not be negative")
+		fmt.Fprintln(os.Stderr, err)
+		return loadConfigError(err)
+	}
+
+	if cfg.watchEnabled() && (cfg.TxSource != txSourceDcrd || cfg.ReplayDir != "") {
+		err = fmt.Errorf("the watchlist needs the %s txsource to receive the new blocks",
+			txSourceDcrd)
+		fmt.Fprintln(os.Stderr, err)
+		return loadConfigError(err)
+	}
+
+	err = analytics.SetFeeModel(analytics.FeeModel{
+		Mode:      analytics.FeeMode(cfg.FeeMode),
+		Tolerance: cfg.FeeTolerance,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return loadConfigError(err)
 	}
 
 	if cfg.DCAHost == "" {
 		cfg.DCAHost = defaultDCAHost + ":" + defaultDCAPort
 	}
 
+	if cfg.NoTLS && !isLocalhost(cfg.DCAHost) {
+		err = fmt.Errorf("notls is only allowed if dcahost is on localhost, "+
+			"found %s", cfg.DCAHost)
+		fmt.Fprintln(os.Stderr, err)
+		return loadConfigError(err)
+	}
+
+	cfg.TLSCert = cleanAndExpandPath(cfg.TLSCert)
+	cfg.TLSKey = cleanAndExpandPath(cfg.TLSKey)
+	cfg.LabelsDir = cleanAndExpandPath(cfg.LabelsDir)
+	cfg.AlertsFile = cleanAndExpandPath(cfg.AlertsFile)
+
 	// Append the network type to the log directory so it is "namespaced"
 	// per network.
 	cfg.LogDir = cleanAndExpandPath(cfg.LogDir)
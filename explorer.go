@@ -11,10 +11,11 @@ import (
 	"strings"
 	"time"
 
-	"github.com/decred/dcrd/rpcclient"
 	"github.com/gorilla/mux"
 	"github.com/raedahgroup/dcrchainanalysis/v1/analytics"
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
 	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+	"github.com/raedahgroup/dcrchainanalysis/v1/watchlist"
 )
 
 const (
@@ -23,13 +24,46 @@ const (
 		`"probability": "/api/v1/{tx-hash}", ` +
 		`"raw solutions": "/api/v1/{tx-hash}/all",` +
 		`"all paths": "/api/v1/{tx}/chain",` +
-		`"single path": "/api/v1/{tx}/chain/{index}"}`
-
-	defaultErrorMsg = `{"error": "Oops! Something went wrong, try different ` +
-		`inputs or contact system maintainers if problem persists.",` +
-		`"duration":"%s"}`
+		`"single path": "/api/v1/{tx}/chain/{index}",` +
+		`"taint": "/api/v1/{tx}/taint/{index}",` +
+		`"most probable path": "/api/v1/{tx}/path/{index}?source={tx|address}",` +
+		`"privacy": "/api/v1/{tx-hash}?privacy=true",` +
+		`"raw tx analysis": "POST /api/v1/analyze",` +
+		`"common ancestry": "/api/v1/common-ancestry?a={tx}:{vout}&b={tx}:{vout}",` +
+		`"watchlist alerts": "/api/v1/alerts",` +
+		`"metrics": "/metrics"}`
+
+	// maxRequestBodySize defines the maximum size of a request body in bytes.
+	maxRequestBodySize = 1 << 20
+
+	// maxSolutionsLimit defines the maximum number of raw solutions per page.
+	maxSolutionsLimit = 1000
+
+	// maxChainDepth defines the maximum depth of the paths discovered.
+	maxChainDepth = 50
+
+	// defaultErrorMsg is returned for the failures that are not typed errors.
+	defaultErrorMsg = "Oops! Something went wrong, try different inputs or " +
+		"contact system maintainers if problem persists."
+
+	// internalErrorCode is the error code of the failures that are not typed
+	// errors.
+	internalErrorCode = "internal_error"
 )
 
+// errorStatus maps the typed error codes to their HTTP status codes.
+var errorStatus = map[rpcutils.ErrorCode]int{
+	rpcutils.ErrInvalidHash:        http.StatusBadRequest,
+	rpcutils.ErrInvalidRequest:     http.StatusBadRequest,
+	rpcutils.ErrInvalidOutputIndex: http.StatusBadRequest,
+	rpcutils.ErrTxNotFound:         http.StatusNotFound,
+	rpcutils.ErrNodeUnavailable:    http.StatusServiceUnavailable,
+	rpcutils.ErrTxTooComplex:       http.StatusUnprocessableEntity,
+	rpcutils.ErrAnalysisTimeout:    http.StatusGatewayTimeout,
+	rpcutils.ErrLabelNotFound:      http.StatusNotFound,
+	rpcutils.ErrPathNotFound:       http.StatusNotFound,
+}
+
 // TimeData defines the time data type that holds the block time from the
 // actual tx and time taken to process a given payload.
 type TimeData struct {
@@ -39,31 +73,100 @@ type TimeData struct {
 
 // explorer defines all the content needed to effectively serve http requests.
 type explorer struct {
-	Client      *rpcclient.Client
+	Client      rpcutils.TxSource
+	Nodes       *rpcutils.NodePool
+	Auth        *apiAuth
+	Labels      *labels.Store
+	Watcher     *watchlist.Watcher
+	Analyses    chan struct{}
 	RPCVersion  *rpcutils.RPCVersion
 	Params      *config
 	OtherParams *extraParams
 }
 
 // rawSolution defines the full structure of final raw solution(single tx
-// analyzed solution).
+// analyzed solution). Total is the number of solutions that matched the query
+// filters and Page and Limit are set if the solutions are paged. Entropy is
+// computed from all the tx solutions.
 type rawSolution struct {
 	TimeData
-	Data []*analytics.AllFundsFlows
+	Total       int
+	Page        int `json:",omitempty"`
+	Limit       int `json:",omitempty"`
+	Data        []*analytics.AllFundsFlows
+	Entropy     *analytics.TxEntropy `json:",omitempty"`
+	Explanation []string             `json:",omitempty"`
 }
 
 // probabilitySolution defines the full structure of the probability solution
-// for a single tx that is based of the raw data solution.
+// for a single tx that is based of the raw data solution. Privacy is only set
+// if the outputs privacy scores were requested.
 type probabilitySolution struct {
 	TimeData
-	Data []*analytics.FlowProbability
+	Fee                *analytics.TxFee `json:",omitempty"`
+	Data               []*analytics.FlowProbability
+	DeterministicLinks []*analytics.DeterministicLink `json:",omitempty"`
+	LinkMatrix         *analytics.LinkMatrix          `json:",omitempty"`
+	Entropy            *analytics.TxEntropy           `json:",omitempty"`
+	Ticket             *analytics.TicketAnalysis      `json:",omitempty"`
+	Labels             *analytics.TxLabels            `json:",omitempty"`
+	Privacy            []*analytics.PrivacyScore      `json:",omitempty"`
+	Explanation        []string                       `json:",omitempty"`
 }
 
 // pathSolution is the funds flow solution that just a chain of probability
-// solutions linked together.
+// solutions linked together. Summary lists the chains sources by address.
 type pathSolution struct {
 	TimeData
-	Data []*analytics.Hub
+	Data    []*analytics.Hub
+	Summary []*analytics.SourceSummary `json:",omitempty"`
+}
+
+// taintSolution defines the taint of a tx output by the tainted sources.
+type taintSolution struct {
+	TimeData
+	Data *analytics.TaintResult
+}
+
+// probablePathSolution defines the most probable path payload.
+type probablePathSolution struct {
+	TimeData
+	Data *analytics.ProbablePath
+}
+
+// ancestrySolution defines the ancestors shared by two outputs payload.
+type ancestrySolution struct {
+	TimeData
+	Data *analytics.CommonAncestry
+}
+
+// alertsSolution defines the watchlist alerts payload.
+type alertsSolution struct {
+	TimeData
+	Data []*watchlist.Alert
+}
+
+// analysisSolution defines the full structure of the funds flow analysis of a
+// transaction that may not be on chain.
+type analysisSolution struct {
+	TimeData
+	Data *analytics.TxAnalysis
+}
+
+// errorResponse defines the payload returned when a request fails. Code is
+// the machine readable name of the error.
+type errorResponse struct {
+	Error    string `json:"error"`
+	Code     string `json:"code"`
+	Duration string `json:"duration"`
+}
+
+// analyzeRequest defines the payload expected by the raw tx analysis endpoint.
+// Amounts holds the previous outpoints amounts of the tx inputs. They are
+// fetched from the transactions source if not provided.
+type analyzeRequest struct {
+	Hex     string    `json:"hex"`
+	Amounts []float64 `json:"amounts"`
 }
 
 // healthHandler helps checks if the system is up and running.
@@ -71,60 +174,548 @@ func (exp *explorer) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	jsonWrite([]byte(healthMsg), http.StatusOK, w)
 }
 
-// StatusHandler handles the various system statuses supported.
+// StatusHandler handles the various system statuses supported. Typed errors
+// are returned with their matching HTTP status and error code, all the other
+// errors are returned as internal server errors.
 func (exp *explorer) StatusHandler(w http.ResponseWriter, r *http.Request,
 	startTime time.Time, err error) {
 	log.Error(err)
 
-	data := fmt.Sprintf(defaultErrorMsg, durationInSec(startTime))
-	jsonWrite([]byte(data), http.StatusUnprocessableEntity, w)
+	status, code, msg := errorDetails(err)
+	errorWrite(errorResponse{
+		Error:    msg,
+		Code:     code,
+		Duration: durationInSec(startTime),
+	}, status, w)
+}
+
+// errorDetails returns the HTTP status, the error code and the error message
+// returned to the clients for the provided error. The messages of the errors
+// that are not typed are not returned.
+func errorDetails(err error) (status int, code, msg string) {
+	if c, ok := rpcutils.ErrorCodeOf(err); ok {
+		return errorStatus[c], c.String(), err.Error()
+	}
+	return http.StatusInternalServerError, internalErrorCode, defaultErrorMsg
+}
+
+// analyze runs fn until it completes or the analysis timeout elapses. An
+// abandoned analysis keeps running in the background until it completes since
+// the analytics functions cannot be interrupted. It keeps holding its
+// Analyses slot until then so that the analyses running at the same time are
+// bounded, the timeout also applies to the wait for a slot. The values set by
+// fn should not be read if an error is returned.
+func (exp *explorer) analyze(fn func() error) error {
+	timeout := exp.Params.AnalysisTimeout
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	timeoutErr := rpcutils.NewError(rpcutils.ErrAnalysisTimeout,
+		fmt.Sprintf("the analysis did not complete within %v", timeout))
+
+	release := func() {}
+	if exp.Analyses != nil {
+		select {
+		case exp.Analyses <- struct{}{}:
+			release = func() { <-exp.Analyses }
+		case <-expired:
+			return timeoutErr
+		}
+	}
+
+	if expired == nil {
+		defer release()
+		return fn()
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		defer release()
+		errChan <- fn()
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-expired:
+		return timeoutErr
+	}
+}
+
+// txAnalysis returns the funds flow analysis of the tx and the tx block time.
+// The tx inputs and outputs entity labels are only set if withLabels is true
+// since the previous txs may need to be fetched. The ticket participants are
+// always flagged since only the labels store is needed.
+func (exp *explorer) txAnalysis(txHash string, withLabels bool) (*analytics.TxAnalysis,
+	int64, error) {
+	var txData *rpcutils.Transaction
+	var analysis *analytics.TxAnalysis
+
+	err := exp.analyze(func() (err error) {
+		analysis, txData, err = analytics.RetrieveTxAnalysis(exp.Client, txHash)
+		if err != nil || len(analysis.Solutions) == 0 {
+			return err
+		}
+
+		err = analytics.TooComplexError(txData.TxID, analysis.Solutions[0].StatusMsg)
+		if err != nil {
+			return err
+		}
+
+		if withLabels {
+			return exp.labelTx(analysis, txData)
+		}
+
+		analysis.Ticket.ApplyLabels(exp.labeler())
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return analysis, txData.BlockTime, nil
+}
+
+// labeler returns the entity labels store or nil if it is not set.
+func (exp *explorer) labeler() analytics.Labeler {
+	if exp.Labels == nil {
+		return nil
+	}
+	return exp.Labels
+}
+
+// labelTx sets the entity labels of the tx inputs and outputs on the analysis
+// and flags the ticket participants labeled as VSPs. The previous txs are only
+// fetched if some addresses are labeled.
+func (exp *explorer) labelTx(analysis *analytics.TxAnalysis, tx *rpcutils.Transaction) (err error) {
+	if exp.Labels.Len() == 0 {
+		return nil
+	}
+
+	analysis.Ticket.ApplyLabels(exp.Labels)
+	analysis.Labels, err = analytics.TxEntityLabels(exp.Client, tx, exp.Labels)
+	return err
+}
+
+// txChain returns the funds flow paths of all the tx outputs or of the output
+// at the index provided and the tx block time. The paths are discovered as
+// defined by the request chain options and the outputs privacy is scored.
+func (exp *explorer) txChain(r *http.Request, outputIndex ...int) ([]*analytics.Hub, int64, error) {
+	opts, err := chainOptions(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts.Privacy = true
+
+	return exp.discoverChain(r, opts, outputIndex...)
+}
+
+// discoverChain returns the funds flow paths of all the tx outputs or of the
+// output at the index provided discovered as defined by the options and the
+// tx block time.
+func (exp *explorer) discoverChain(r *http.Request, opts analytics.ChainOptions,
+	outputIndex ...int) ([]*analytics.Hub, int64, error) {
+	opts.Labels = exp.labeler()
+
+	var chain []*analytics.Hub
+	var txTime int64
+
+	err := exp.analyze(func() (err error) {
+		chain, txTime, err = analytics.ChainDiscoveryWithOptions(exp.Client,
+			mux.Vars(r)["tx"], opts, outputIndex...)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return chain, txTime, nil
+}
+
+// txPrivacy returns the privacy scores of all the tx outputs. The outputs paths
+// are discovered as defined by the request chain options.
+func (exp *explorer) txPrivacy(r *http.Request) ([]*analytics.PrivacyScore, int64, error) {
+	chain, txTime, err := exp.txChain(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	scores := make([]*analytics.PrivacyScore, len(chain))
+	for i, hub := range chain {
+		scores[i] = hub.Privacy
+	}
+	return scores, txTime, nil
+}
+
+// txTaint returns the taint of the tx output at the index in the request path
+// and the tx block time. The tainted sources and the policy are set by the
+// request query and the output chain is discovered as defined by the request
+// chain options. The certain links are followed since the tainted sources may
+// be further back.
+func (exp *explorer) txTaint(r *http.Request) (*analytics.TaintResult, int64, error) {
+	txIndex, err := outputIndex(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sources, policy := taintQuery(r)
+	if err = analytics.ValidateTaint(sources, policy); err != nil {
+		return nil, 0, err
+	}
+
+	opts, err := chainOptions(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts.FollowCertain = true
+	opts.Labels = exp.labeler()
+
+	var result *analytics.TaintResult
+	var txTime int64
+
+	// The FIFO policy fetches the chain txs again thus the taint is computed
+	// within the analysis too.
+	err = exp.analyze(func() error {
+		chain, blockTime, err := analytics.ChainDiscoveryWithOptions(exp.Client,
+			mux.Vars(r)["tx"], opts, txIndex)
+		if err != nil {
+			return err
+		}
+
+		txTime = blockTime
+		result, err = analytics.ComputeTaint(exp.Client, chain[0], sources, policy)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result, txTime, nil
+}
+
+// txPath returns the most probable path from the tx output at the index in
+// the request path to the source set by the source query parameter and the tx
+// block time. The depth and the other chain options of the request apply.
+func (exp *explorer) txPath(r *http.Request) (*analytics.ProbablePath, int64, error) {
+	txIndex, err := outputIndex(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts, err := chainOptions(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts.Labels = exp.labeler()
+
+	var path *analytics.ProbablePath
+	var txTime int64
+
+	err = exp.analyze(func() (err error) {
+		path, txTime, err = analytics.MostProbablePath(exp.Client, mux.Vars(r)["tx"],
+			txIndex, r.URL.Query().Get("source"), opts)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return path, txTime, nil
+}
+
+// taintQuery parses the tainted outpoints and addresses and the taint policy
+// query parameters. The haircut policy is used if none is set.
+func taintQuery(r *http.Request) (analytics.TaintSources, analytics.TaintPolicy) {
+	params := r.URL.Query()
+
+	policy := analytics.TaintPolicy(params.Get("policy"))
+	if policy == "" {
+		policy = analytics.TaintHaircut
+	}
+
+	return analytics.TaintSources{
+		Outpoints: params["outpoint"],
+		Addresses: params["address"],
+	}, policy
+}
+
+// solutionsQuery parses the raw solutions filtering, sorting and pagination
+// query parameters.
+func solutionsQuery(r *http.Request) (q analytics.SolutionsQuery, err error) {
+	params := r.URL.Query()
+
+	parseInt := func(name string) int {
+		v := params.Get(name)
+		if v == "" || err != nil {
+			return 0
+		}
+
+		n, e := strconv.Atoi(v)
+		if e != nil {
+			err = rpcutils.NewError(rpcutils.ErrInvalidRequest,
+				fmt.Sprintf("invalid %s %q: %v", name, v, e))
+		}
+		return n
+	}
+
+	parseFloat := func(name string) float64 {
+		v := params.Get(name)
+		if v == "" || err != nil {
+			return 0
+		}
+
+		n, e := strconv.ParseFloat(v, 64)
+		if e != nil {
+			err = rpcutils.NewError(rpcutils.ErrInvalidRequest,
+				fmt.Sprintf("invalid %s %q: %v", name, v, e))
+		}
+		return n
+	}
+
+	q.Page = parseInt("page")
+	q.Limit = parseInt("limit")
+	q.Input = parseFloat("input")
+	q.Output = parseFloat("output")
+	q.SortBy = params.Get("sort")
+
+	if q.Limit > maxSolutionsLimit && err == nil {
+		err = rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			fmt.Sprintf("invalid limit %d: expected at most %d", q.Limit, maxSolutionsLimit))
+	}
+
+	switch order := params.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		if err == nil {
+			err = rpcutils.NewError(rpcutils.ErrInvalidRequest,
+				fmt.Sprintf("invalid order %q: expected asc or desc", order))
+		}
+	}
+
+	return q, err
+}
+
+// querySolutions returns the page of the raw solutions of the tx that matched
+// the request query parameters and the tx block time.
+func (exp *explorer) querySolutions(r *http.Request) (*analytics.SolutionsPage, int64, error) {
+	q, err := solutionsQuery(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	analysis, txTime, err := exp.txAnalysis(mux.Vars(r)["tx"], false)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, err := analytics.QuerySolutions(analysis.Solutions, q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page.Entropy = analysis.Entropy
+	return page, txTime, nil
+}
+
+// chainOptions parses the chain discovery query parameters.
+func chainOptions(r *http.Request) (opts analytics.ChainOptions, err error) {
+	opts.DeterministicOnly, err = queryBool(r, "deterministic")
+	if err != nil {
+		return opts, err
+	}
+
+	opts.StopAtLabels, err = queryBool(r, "stoplabeled")
+	if err != nil {
+		return opts, err
+	}
+
+	if v := r.URL.Query().Get("depth"); v != "" {
+		opts.MaxDepth, err = strconv.Atoi(v)
+		if err != nil || opts.MaxDepth < 0 {
+			return opts, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+				fmt.Sprintf("invalid depth %q: expected a non-negative integer", v))
+		}
+
+		if opts.MaxDepth > maxChainDepth {
+			return opts, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+				fmt.Sprintf("invalid depth %d: expected at most %d", opts.MaxDepth,
+					maxChainDepth))
+		}
+	}
+	return opts, nil
+}
+
+// explainRequested checks if the plain language explanation of the analysis
+// was requested with the explain query parameter.
+func explainRequested(r *http.Request) (bool, error) {
+	return queryBool(r, "explain")
+}
+
+// queryBool parses the boolean query parameter. False is returned if the
+// parameter is not set.
+func queryBool(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			fmt.Sprintf("invalid %s %q: %v", name, v, err))
+	}
+	return b, nil
+}
+
+// outputIndex parses the output index in the request path.
+func outputIndex(r *http.Request) (int, error) {
+	txIndex, err := strconv.Atoi(mux.Vars(r)["index"])
+	if err != nil {
+		return 0, rpcutils.NewError(rpcutils.ErrInvalidOutputIndex,
+			fmt.Sprintf("invalid output index: %v", err))
+	}
+	return txIndex, nil
+}
+
+// analyzeRawTx decodes the raw tx analysis request payload and returns the
+// funds flow analysis of the tx.
+func (exp *explorer) analyzeRawTx(w http.ResponseWriter, r *http.Request) (
+	*analytics.TxAnalysis, error) {
+	var req analyzeRequest
+	body := http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			fmt.Sprintf("invalid request payload: %v", err))
+	}
+
+	explain, err := explainRequested(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var analysis *analytics.TxAnalysis
+
+	err = exp.analyze(func() (err error) {
+		var tx *rpcutils.Transaction
+		analysis, tx, err = analytics.AnalyzeRawTx(exp.Client, req.Hex, req.Amounts,
+			exp.OtherParams.ActiveNet)
+		if err != nil {
+			return err
+		}
+
+		err = analytics.TooComplexError(analysis.TxID, analysis.Solutions[0].StatusMsg)
+		if err != nil {
+			return err
+		}
+		return exp.labelTx(analysis, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if explain {
+		analysis.Explanation = analytics.ExplainAnalysis(analysis)
+	}
+
+	return analysis, nil
 }
 
 // AllTxSolutionsHandler fetches analyzed transactions inputs and outputs returning
-// all the possible solutions generated(raw tx solution).
+// all the possible solutions generated(raw tx solution). The solutions can be
+// filtered by the input and output amounts, sorted by buckets or fees and paged
+// with the query parameters.
 func (exp *explorer) AllTxSolutionsHandler(w http.ResponseWriter, r *http.Request) {
-	transactionX := mux.Vars(r)["tx"]
 	t := time.Now()
 
-	txData, err := analytics.RetrieveTxData(exp.Client, transactionX)
+	explain, err := explainRequested(r)
 	if err != nil {
 		exp.StatusHandler(w, r, t, err)
 		return
 	}
 
-	rawTxSolution, _, _, err := analytics.TransactionFundsFlow(txData)
+	page, txTime, err := exp.querySolutions(r)
 	if err != nil {
 		exp.StatusHandler(w, r, t, err)
 		return
 	}
 
+	var explanation []string
+	if explain {
+		explanation = analytics.ExplainSolutions(page.Solutions, page.Total)
+	}
+
 	exp.handleJSONWrite(
 		rawSolution{
-			Data: rawTxSolution,
+			Data:        page.Solutions,
+			Total:       page.Total,
+			Page:        page.Page,
+			Limit:       page.Limit,
+			Entropy:     page.Entropy,
+			Explanation: explanation,
 			TimeData: TimeData{
-				TxTime: txData.BlockTime, Duration: durationInSec(t),
+				TxTime: txTime, Duration: durationInSec(t),
 			},
 		},
 		http.StatusOK, t, w, r)
 }
 
 // TxProbabilityHandler from the fetched analyzed solutions, it returns the solution
-// with the lowest granularity as the best solution.
+// with the lowest granularity as the best solution. The outputs privacy scores
+// are added if the privacy query parameter is set.
 func (exp *explorer) TxProbabilityHandler(w http.ResponseWriter, r *http.Request) {
-	transactionX := mux.Vars(r)["tx"]
 	t := time.Now()
 
-	solProbability, txData, err := analytics.RetrieveTxProbability(exp.Client, transactionX)
+	explain, err := explainRequested(r)
 	if err != nil {
 		exp.StatusHandler(w, r, t, err)
 		return
 	}
 
+	privacy, err := queryBool(r, "privacy")
+	if err != nil {
+		exp.StatusHandler(w, r, t, err)
+		return
+	}
+
+	analysis, txTime, err := exp.txAnalysis(mux.Vars(r)["tx"], true)
+	if err != nil {
+		exp.StatusHandler(w, r, t, err)
+		return
+	}
+
+	var scores []*analytics.PrivacyScore
+	if privacy {
+		if scores, _, err = exp.txPrivacy(r); err != nil {
+			exp.StatusHandler(w, r, t, err)
+			return
+		}
+	}
+
+	var explanation []string
+	if explain {
+		explanation = analytics.ExplainProbabilities(analysis.Probabilities)
+	}
+
 	exp.handleJSONWrite(
 		probabilitySolution{
-			Data: solProbability,
+			Fee:                analysis.Fee,
+			Data:               analysis.Probabilities,
+			DeterministicLinks: analysis.DeterministicLinks,
+			LinkMatrix:         analysis.LinkMatrix,
+			Entropy:            analysis.Entropy,
+			Ticket:             analysis.Ticket,
+			Labels:             analysis.Labels,
+			Privacy:            scores,
+			Explanation:        explanation,
 			TimeData: TimeData{
-				TxTime: txData.BlockTime, Duration: durationInSec(t),
+				TxTime: txTime, Duration: durationInSec(t),
 			},
 		},
 		http.StatusOK, t, w, r)
@@ -132,10 +723,9 @@ func (exp *explorer) TxProbabilityHandler(w http.ResponseWriter, r *http.Request
 
 // ChainHandler reconstructs the probability solution to create funds flow paths.
 func (exp *explorer) ChainHandler(w http.ResponseWriter, r *http.Request) {
-	transactionX := mux.Vars(r)["tx"]
 	t := time.Now()
 
-	chain, TxTime, err := analytics.ChainDiscovery(exp.Client, transactionX)
+	chain, txTime, err := exp.txChain(r)
 	if err != nil {
 		exp.StatusHandler(w, r, t, err)
 		return
@@ -143,28 +733,27 @@ func (exp *explorer) ChainHandler(w http.ResponseWriter, r *http.Request) {
 
 	exp.handleJSONWrite(
 		pathSolution{
-			Data: chain,
+			Data:    chain,
+			Summary: analytics.ChainSources(chain),
 			TimeData: TimeData{
-				TxTime: TxTime, Duration: durationInSec(t),
+				TxTime: txTime, Duration: durationInSec(t),
 			},
 		},
 		http.StatusOK, t, w, r)
 }
 
 // ChainPathHandler reconstructs the probability solution to create one funds
-// flow path on the provided outputs index. If the index provided in greater than
-// the available output index, the outputs path with the last index is returned.
+// flow path on the provided outputs index.
 func (exp *explorer) ChainPathHandler(w http.ResponseWriter, r *http.Request) {
-	transactionX := mux.Vars(r)["tx"]
 	t := time.Now()
 
-	txIndex, err := strconv.Atoi(mux.Vars(r)["index"])
+	txIndex, err := outputIndex(r)
 	if err != nil {
 		exp.StatusHandler(w, r, t, err)
 		return
 	}
 
-	chain, TxTime, err := analytics.ChainDiscovery(exp.Client, transactionX, txIndex)
+	chain, txTime, err := exp.txChain(r, txIndex)
 	if err != nil {
 		exp.StatusHandler(w, r, t, err)
 		return
@@ -172,14 +761,144 @@ func (exp *explorer) ChainPathHandler(w http.ResponseWriter, r *http.Request) {
 
 	exp.handleJSONWrite(
 		pathSolution{
-			Data: chain,
+			Data:    chain,
+			Summary: analytics.ChainSources(chain),
+			TimeData: TimeData{
+				TxTime: txTime, Duration: durationInSec(t),
+			},
+		},
+		http.StatusOK, t, w, r)
+}
+
+// TaintHandler returns the taint of the tx output at the provided index by the
+// tainted outpoints and addresses in the request query.
+func (exp *explorer) TaintHandler(w http.ResponseWriter, r *http.Request) {
+	t := time.Now()
+
+	result, txTime, err := exp.txTaint(r)
+	if err != nil {
+		exp.StatusHandler(w, r, t, err)
+		return
+	}
+
+	exp.handleJSONWrite(
+		taintSolution{
+			Data: result,
 			TimeData: TimeData{
-				TxTime: TxTime, Duration: durationInSec(t),
+				TxTime: txTime, Duration: durationInSec(t),
 			},
 		},
 		http.StatusOK, t, w, r)
 }
 
+// PathHandler returns the most probable path from the tx output at the index to
+// the source tx or address set by the source query parameter.
+func (exp *explorer) PathHandler(w http.ResponseWriter, r *http.Request) {
+	t := time.Now()
+
+	path, txTime, err := exp.txPath(r)
+	if err != nil {
+		exp.StatusHandler(w, r, t, err)
+		return
+	}
+
+	exp.handleJSONWrite(
+		probablePathSolution{
+			Data: path,
+			TimeData: TimeData{
+				TxTime: txTime, Duration: durationInSec(t),
+			},
+		},
+		http.StatusOK, t, w, r)
+}
+
+// CommonAncestryHandler returns the ancestors shared by the outputs set by the
+// a and b query parameters as "txhash:vout". Both outputs are traced back as
+// defined by the request chain options.
+func (exp *explorer) CommonAncestryHandler(w http.ResponseWriter, r *http.Request) {
+	t := time.Now()
+
+	params := r.URL.Query()
+	a, b := params.Get("a"), params.Get("b")
+	if a == "" || b == "" {
+		exp.StatusHandler(w, r, t, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+			"both the a and b outputs are required as txhash:vout"))
+		return
+	}
+
+	opts, err := chainOptions(r)
+	if err != nil {
+		exp.StatusHandler(w, r, t, err)
+		return
+	}
+	opts.Labels = exp.labeler()
+
+	var ancestry *analytics.CommonAncestry
+	err = exp.analyze(func() (err error) {
+		ancestry, err = analytics.FindCommonAncestry(exp.Client, a, b, opts)
+		return err
+	})
+	if err != nil {
+		exp.StatusHandler(w, r, t, err)
+		return
+	}
+
+	exp.handleJSONWrite(
+		ancestrySolution{
+			Data:     ancestry,
+			TimeData: TimeData{Duration: durationInSec(t)},
+		},
+		http.StatusOK, t, w, r)
+}
+
+// AlertsHandler returns the watchlist alerts, oldest first. The since query
+// parameter sets the ID after which the alerts are returned. No alerts are
+// returned if the watchlist is not enabled.
+func (exp *explorer) AlertsHandler(w http.ResponseWriter, r *http.Request) {
+	t := time.Now()
+
+	var since int
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		if since, err = strconv.Atoi(s); err != nil || since < 0 {
+			exp.StatusHandler(w, r, t, rpcutils.NewError(rpcutils.ErrInvalidRequest,
+				fmt.Sprintf("invalid since alert ID %q", s)))
+			return
+		}
+	}
+
+	alerts := []*watchlist.Alert{}
+	if exp.Watcher != nil {
+		alerts = exp.Watcher.Alerts(since)
+	}
+
+	exp.handleJSONWrite(
+		alertsSolution{
+			Data:     alerts,
+			TimeData: TimeData{Duration: durationInSec(t)},
+		},
+		http.StatusOK, t, w, r)
+}
+
+// AnalyzeHandler decodes the raw tx hex posted and returns its funds flow
+// solutions and probabilities. The tx does not need to be on chain.
+func (exp *explorer) AnalyzeHandler(w http.ResponseWriter, r *http.Request) {
+	t := time.Now()
+
+	analysis, err := exp.analyzeRawTx(w, r)
+	if err != nil {
+		exp.StatusHandler(w, r, t, err)
+		return
+	}
+
+	exp.handleJSONWrite(
+		analysisSolution{
+			Data:     analysis,
+			TimeData: TimeData{Duration: durationInSec(t)},
+		},
+		http.StatusOK, t, w, r)
+}
+
 // PprofHandler fetches the correct pprof handler needed.
 func (exp *explorer) PprofHandler(w http.ResponseWriter, r *http.Request) {
 	handlerType := mux.Vars(r)["name"]
@@ -215,6 +934,17 @@ func jsonWrite(data []byte, status int, w http.ResponseWriter) {
 	w.Write(data)
 }
 
+// errorWrite sends back the error payload with the provided status.
+func errorWrite(resp errorResponse, status int, w http.ResponseWriter) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Errorf("encoding the error response failed: %v", err)
+		return
+	}
+
+	jsonWrite(data, status, w)
+}
+
 // durationInSec calculates the duration in seconds.
 func durationInSec(t time.Time) string {
 	d := time.Since(t)
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/networkconfig"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+	"github.com/raedahgroup/dcrchainanalysis/v1/watchlist"
+)
+
+// replayTxID is the recorded testnet tx served to the handlers.
+const replayTxID = "0fbd7e758a016c6a0b59a56eaf0ceaa562250cc44d75de810ff53ad7e1d9a231"
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	// The log rotator is not initialized in the tests.
+	setLogLevels("off")
+
+	os.Exit(m.Run())
+}
+
+// newTestExplorer returns an explorer serving the recorded transactions.
+func newTestExplorer() *explorer {
+	return &explorer{
+		Client:      rpcutils.NewReplayer(filepath.Join("testdata", "replay")),
+		Params:      &config{},
+		OtherParams: &extraParams{ActiveNet: networkconfig.TestNet},
+	}
+}
+
+// serve sends the request to the explorer router and returns the response.
+func serve(exp *explorer, method, path string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	newRouter(exp).ServeHTTP(w, req)
+	return w
+}
+
+// checkGolden compares the JSON payload with the golden file contents ignoring
+// the request duration. The golden file is rewritten instead if the -update
+// flag is set.
+func checkGolden(t *testing.T, name string, payload []byte) {
+	matchGolden(t, filepath.Join("testdata", "http", name+".golden"),
+		withoutDuration(t, payload))
+}
+
+// withoutDuration returns the JSON payload indented without its duration.
+func withoutDuration(t *testing.T, payload []byte) []byte {
+	var data map[string]interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		t.Fatalf("expected a JSON payload but found %s", string(payload))
+	}
+
+	delete(data, "Duration")
+	delete(data, "duration")
+
+	// The v2 API responses hold the duration in the meta data.
+	if meta, ok := data["meta"].(map[string]interface{}); ok {
+		delete(meta, "duration")
+	}
+
+	result, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		t.Fatalf("expected no error encoding the payload but found %v", err)
+	}
+	return result
+}
+
+// matchGolden compares the result with the golden file contents. The golden
+// file is rewritten instead if the -update flag is set.
+func matchGolden(t *testing.T, golden string, result []byte) {
+	if *updateGolden {
+		if err := ioutil.WriteFile(golden, append(result, '\n'), 0644); err != nil {
+			t.Fatalf("expected no error updating %s but found %v", golden, err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("expected no error reading %s but found %v", golden, err)
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(expected), result) {
+		t.Fatalf("expected the payload to match %s but found %s", golden, string(result))
+	}
+}
+
+// TestHandlersReplay runs the API handlers against the recorded transactions
+// and compares their payloads with the golden files.
+func TestHandlersReplay(t *testing.T) {
+	type testData struct {
+		Name   string
+		Path   string
+		Status int
+	}
+
+	td := []testData{
+		{Name: "probability", Path: "/api/v1/" + replayTxID, Status: http.StatusOK},
+		{Name: "probability_explain", Path: "/api/v1/" + replayTxID + "?explain=true",
+			Status: http.StatusOK},
+		{Name: "invalid_explain", Path: "/api/v1/" + replayTxID + "?explain=maybe",
+			Status: http.StatusBadRequest},
+		{Name: "probability_privacy", Path: "/api/v1/" + replayTxID + "?privacy=true",
+			Status: http.StatusOK},
+		{Name: "invalid_privacy", Path: "/api/v1/" + replayTxID + "?privacy=maybe",
+			Status: http.StatusBadRequest},
+		{Name: "all", Path: "/api/v1/" + replayTxID + "/all", Status: http.StatusOK},
+		{Name: "all_explain", Path: "/api/v1/" + replayTxID + "/all?limit=2&explain=1",
+			Status: http.StatusOK},
+		{Name: "all_query", Status: http.StatusOK,
+			Path: "/api/v1/" + replayTxID + "/all?sort=buckets&order=desc&output=40.9873785&limit=1&page=1"},
+		{Name: "all_invalid_query", Path: "/api/v1/" + replayTxID + "/all?limit=ten",
+			Status: http.StatusBadRequest},
+		{Name: "all_huge_page", Status: http.StatusOK,
+			Path: "/api/v1/" + replayTxID + "/all?limit=4&page=2305843009213693953"},
+		{Name: "all_limit_too_large", Path: "/api/v1/" + replayTxID + "/all?limit=1001",
+			Status: http.StatusBadRequest},
+		{Name: "chain", Path: "/api/v1/" + replayTxID + "/chain", Status: http.StatusOK},
+		{Name: "chain_deterministic", Status: http.StatusOK,
+			Path: "/api/v1/" + replayTxID + "/chain/3?deterministic=true&depth=2"},
+		{Name: "chain_invalid_depth", Path: "/api/v1/" + replayTxID + "/chain?depth=-1",
+			Status: http.StatusBadRequest},
+		{Name: "chain_depth_too_large", Status: http.StatusBadRequest,
+			Path: "/api/v1/" + replayTxID + "/chain?deterministic=true&depth=100000"},
+		{Name: "taint_depth_too_large", Status: http.StatusBadRequest,
+			Path: "/api/v1/" + replayTxID + "/taint/0?depth=100000" +
+				"&address=TsZXXprc8dCQXLpNRatG9nP2MUY7dV2Angp"},
+		{Name: "chain_index", Path: "/api/v1/" + replayTxID + "/chain/3", Status: http.StatusOK},
+		{Name: "taint", Status: http.StatusOK,
+			Path: "/api/v1/" + replayTxID + "/taint/1?address=TsZXXprc8dCQXLpNRatG9nP2MUY7dV2Angp" +
+				"&outpoint=932345119a257868a9c38ab51a0fce78c6ac3db331a04dc4603734d34a177014:0"},
+		{Name: "taint_fifo", Status: http.StatusOK,
+			Path: "/api/v1/" + replayTxID + "/taint/1?policy=fifo" +
+				"&address=TsZXXprc8dCQXLpNRatG9nP2MUY7dV2Angp"},
+		{Name: "taint_invalid_policy", Status: http.StatusBadRequest,
+			Path: "/api/v1/" + replayTxID + "/taint/1?policy=lifo&address=TsZXXprc8dCQXLpNRatG9nP2MUY7dV2Angp"},
+		{Name: "taint_no_sources", Path: "/api/v1/" + replayTxID + "/taint/1",
+			Status: http.StatusBadRequest},
+		{Name: "path", Status: http.StatusOK,
+			Path: "/api/v1/" + replayTxID + "/path/1?source=TsZXXprc8dCQXLpNRatG9nP2MUY7dV2Angp"},
+		{Name: "path_no_source", Path: "/api/v1/" + replayTxID + "/path/1",
+			Status: http.StatusBadRequest},
+		{Name: "path_not_found", Status: http.StatusNotFound,
+			Path: "/api/v1/" + replayTxID + "/path/1?depth=1&source=TsZXXprc8dCQXLpNRatG9nP2MUY7dV2Angp"},
+		{Name: "common_ancestry", Status: http.StatusOK,
+			Path: "/api/v1/common-ancestry?depth=1&a=" + replayTxID + ":1&b=" + replayTxID + ":2"},
+		{Name: "common_ancestry_missing", Path: "/api/v1/common-ancestry?a=" + replayTxID + ":1",
+			Status: http.StatusBadRequest},
+		{Name: "common_ancestry_invalid", Status: http.StatusBadRequest,
+			Path: "/api/v1/common-ancestry?a=" + replayTxID + ":1&b=" + replayTxID},
+		{Name: "alerts_disabled", Path: "/api/v1/alerts", Status: http.StatusOK},
+		{Name: "alerts_invalid_since", Path: "/api/v1/alerts?since=x",
+			Status: http.StatusBadRequest},
+		{Name: "unknown_tx", Status: http.StatusNotFound,
+			Path: "/api/v1/ae40333aed99c0b004ef01834444944c471bc72ce869949f6f8ced728941f561"},
+		{Name: "invalid_hash", Path: "/api/v1/not-a-hash", Status: http.StatusBadRequest},
+		{Name: "invalid_index", Path: "/api/v1/" + replayTxID + "/chain/10",
+			Status: http.StatusBadRequest},
+	}
+
+	exp := newTestExplorer()
+
+	for _, data := range td {
+		t.Run(data.Name, func(t *testing.T) {
+			w := serve(exp, "GET", data.Path, nil)
+			if w.Code != data.Status {
+				t.Fatalf("expected status %d but found %d: %s", data.Status, w.Code,
+					w.Body.String())
+			}
+
+			checkGolden(t, data.Name, w.Body.Bytes())
+		})
+	}
+}
+
+// testRawTx is a serialized testnet transaction spending 39.96949337,
+// 40.9873785 and 5076.66042217 DCR.
+const testRawTx = "01000000038fe7042d078343dc3b01f8c9c905b867c0e50337d8c55c25f5d4add5" +
+	"51e83c080000000000ffffffffe7644b840cc8a171cc85ac02c1042616eeb0423bf073" +
+	"9bdaa103f98513a5caea0100000000ffffffff01d5f72cbffdf27e2e410cd03f3a6534" +
+	"7290b564b4e9d1e3a3aa2d26c0dfee2e0200000000ffffffff04adf73bee0000000000" +
+	"001976a9143c8eed4311e04776d23426b4da32d89df0db187388acbac64df400000000" +
+	"00001976a914d5a6063b8dabaaafd40beaf5c3e211aefcdcc24d88acbac64df4000000" +
+	"0000001976a914afb2599b857425f6df0f13e483c92a91b09f487f88acdbe3f23e7500" +
+	"000000001976a91472070b6afa984b7ed7f76dd3ee38591d02f1024d88ac0000000000" +
+	"00000003599b3cee0000000000000000ffffffff0151bac64df4000000000000000" +
+	"0ffffffff0151690d41337600000000000000ffffffff0151"
+
+// TestAnalyzeHandler runs the raw tx analysis handler with the previous
+// outpoints amounts posted and compares its payloads with the golden files.
+func TestAnalyzeHandler(t *testing.T) {
+	type testData struct {
+		Name    string
+		Amounts []float64
+		Status  int
+	}
+
+	td := []testData{
+		{Name: "analyze", Amounts: []float64{39.96949337, 40.9873785, 5076.66042217},
+			Status: http.StatusOK},
+		{Name: "analyze_missing_amounts", Amounts: []float64{39.96949337},
+			Status: http.StatusBadRequest},
+		{Name: "analyze_negative_amount", Amounts: []float64{-5, 40.9873785, 5076.66042217},
+			Status: http.StatusBadRequest},
+		{Name: "analyze_amounts_below_outputs", Amounts: []float64{1, 1, 1},
+			Status: http.StatusBadRequest},
+	}
+
+	exp := newTestExplorer()
+
+	for _, data := range td {
+		t.Run(data.Name, func(t *testing.T) {
+			body, err := json.Marshal(analyzeRequest{Hex: testRawTx, Amounts: data.Amounts})
+			if err != nil {
+				t.Fatalf("expected no error encoding the request but found %v", err)
+			}
+
+			w := serve(exp, "POST", "/api/v1/analyze", body)
+			if w.Code != data.Status {
+				t.Fatalf("expected status %d but found %d: %s", data.Status, w.Code,
+					w.Body.String())
+			}
+
+			checkGolden(t, data.Name, w.Body.Bytes())
+		})
+	}
+}
+
+// TestNodeUnavailable tests that the requests fail with the node unavailable
+// error when the transactions source cannot be reached.
+func TestNodeUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	exp := newTestExplorer()
+	exp.Client = rpcutils.NewDcrdataClient(server.URL)
+
+	w := serve(exp, "GET", "/api/v1/"+replayTxID, nil)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d but found %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	var resp errorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("expected no error decoding the payload but found %v", err)
+	}
+
+	if resp.Code != rpcutils.ErrNodeUnavailable.String() {
+		t.Fatalf("expected the %v error code but found %s", rpcutils.ErrNodeUnavailable,
+			resp.Code)
+	}
+}
+
+// TestAnalysisTimeout tests that the analyses that take longer than the
+// analysis timeout return the analysis timeout error.
+func TestAnalysisTimeout(t *testing.T) {
+	exp := newTestExplorer()
+	exp.Params.AnalysisTimeout = time.Millisecond
+
+	done := make(chan struct{})
+	defer close(done)
+
+	err := exp.analyze(func() error {
+		<-done
+		return nil
+	})
+
+	if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != rpcutils.ErrAnalysisTimeout {
+		t.Fatalf("expected the %v error but found %v", rpcutils.ErrAnalysisTimeout, err)
+	}
+}
+
+// TestMaxAnalyses tests that an analysis waiting for a slot while the max
+// analyses are running returns the analysis timeout error without running.
+func TestMaxAnalyses(t *testing.T) {
+	exp := newTestExplorer()
+	exp.Params.AnalysisTimeout = time.Millisecond
+	exp.Analyses = make(chan struct{}, 1)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// The abandoned analysis keeps its slot until it completes.
+	exp.analyze(func() error {
+		<-done
+		return nil
+	})
+
+	var ran bool
+	err := exp.analyze(func() error {
+		ran = true
+		return nil
+	})
+
+	if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != rpcutils.ErrAnalysisTimeout {
+		t.Fatalf("expected the %v error but found %v", rpcutils.ErrAnalysisTimeout, err)
+	}
+
+	if ran {
+		t.Fatal("expected the analysis not to run while no slot is free")
+	}
+}
+
+// TestMetrics tests that the analysis, chain discovery and HTTP metrics are
+// exposed once the requests that record them are served.
+func TestMetrics(t *testing.T) {
+	exp := newTestExplorer()
+	serve(exp, "GET", "/api/v1/"+replayTxID+"/chain/3", nil)
+
+	w := serve(exp, "GET", "/metrics", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d but found %d", http.StatusOK, w.Code)
+	}
+
+	td := []string{
+		`dca_analysis_phase_duration_seconds_count{phase="find_x"}`,
+		`dca_analysis_solutions_count`,
+		`dca_chain_depth_count`,
+		`dca_chain_hubs_count`,
+		`dca_http_request_duration_seconds_count{code="200",method="GET",route="/api/v1/{tx}/chain/{index:[0-9]+}"}`,
+	}
+
+	for i, metric := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			if !strings.Contains(w.Body.String(), metric) {
+				t.Fatalf("expected the metrics to contain %s but found none", metric)
+			}
+		})
+	}
+}
+
+// TestAlertsHandler tests that the saved watchlist alerts are listed after the
+// since alert ID.
+func TestAlertsHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alerts")
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	alertsFile := filepath.Join(dir, "alerts.json")
+	saved := `{"ID":1,"TxHash":"` + replayTxID + `","Vout":0,"Amount":1,"Probability":1}` + "\n" +
+		`{"ID":2,"TxHash":"` + replayTxID + `","Vout":1,"Amount":2,"Probability":0.95}` + "\n"
+	if err = ioutil.WriteFile(alertsFile, []byte(saved), 0600); err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	exp := newTestExplorer()
+	exp.Watcher, err = watchlist.New(watchlist.Config{MinProbability: 0.9,
+		AlertsFile: alertsFile}, nil)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	w := serve(exp, "GET", "/api/v1/alerts?since=1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d but found %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var payload alertsSolution
+	if err = json.Unmarshal(w.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("expected a JSON payload but found %s", w.Body.String())
+	}
+
+	if len(payload.Data) != 1 || payload.Data[0].ID != 2 || payload.Data[0].Vout != 1 {
+		t.Fatalf("expected alert 2 but found %+v", payload.Data)
+	}
+}
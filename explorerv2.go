@@ -0,0 +1,425 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/raedahgroup/dcrchainanalysis/v1/analytics"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+const (
+	// apiV2Version is the version of the v2 API reported in the responses
+	// meta data and the OpenAPI document.
+	apiV2Version = "2.0.0"
+
+	// openAPIPath is the path of the v2 API OpenAPI document.
+	openAPIPath = "/api/v2/openapi.json"
+)
+
+// v2Envelope defines the structure of all the v2 API responses. Data is not
+// set if the request failed and Errors is not set if the request succeeded.
+type v2Envelope struct {
+	Data   interface{} `json:"data,omitempty"`
+	Meta   v2Meta      `json:"meta"`
+	Errors []v2Error   `json:"errors,omitempty"`
+}
+
+// v2Meta holds the v2 API responses meta data. Explanation is only set if the
+// plain language explanation of the analysis was requested. Summary lists the
+// sources by address of the chain responses.
+type v2Meta struct {
+	APIVersion  string
+	TxTime      int64 `json:",omitempty"`
+	Duration    string
+	Explanation []string                   `json:",omitempty"`
+	Summary     []*analytics.SourceSummary `json:",omitempty"`
+}
+
+// v2Error defines a single v2 API error. Code is the machine readable name of
+// the error.
+type v2Error struct {
+	Code    string
+	Message string
+}
+
+// v2Health defines the v2 API health check payload.
+type v2Health struct {
+	Status    string
+	Endpoints []string
+}
+
+// v2Param defines a v2 route query parameter.
+type v2Param struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// v2HandlerFunc returns the data of a v2 API request and its meta data i.e. the
+// block time of the tx analyzed and the explanation of the analysis if any.
+type v2HandlerFunc func(exp *explorer, w http.ResponseWriter, r *http.Request) (
+	interface{}, v2Meta, error)
+
+// v2Route defines a v2 API route. Data and Body are zero values of the types
+// of the response data and of the request payload used to generate the OpenAPI
+// document.
+type v2Route struct {
+	Method  string
+	Path    string
+	Summary string
+	Query   []v2Param
+	Body    interface{}
+	Data    interface{}
+	handler v2HandlerFunc
+}
+
+// solutionsQueryParams lists the raw solutions query parameters.
+var solutionsQueryParams = []v2Param{
+	{Name: "page", Type: "integer", Description: "1-based page number, used with limit"},
+	{Name: "limit", Type: "integer", Description: "Number of solutions per page, at most 1000, 0 returns all"},
+	{Name: "sort", Type: "string", Description: "Sort the solutions by buckets or fees"},
+	{Name: "order", Type: "string", Description: "Sort order, asc or desc"},
+	{Name: "input", Type: "number", Description: "Keep the solutions with a bucket holding the input amount"},
+	{Name: "output", Type: "number", Description: "Keep the solutions with a bucket holding the output amount"},
+	explainQueryParam,
+}
+
+// chainQueryParams lists the chain discovery query parameters.
+var chainQueryParams = []v2Param{
+	{Name: "deterministic", Type: "boolean", Description: "Follow only the deterministic links"},
+	{Name: "depth", Type: "integer", Description: "Maximum depth of the paths, at most 50. " +
+		"0 for no limit, or 10 if only the deterministic links are followed"},
+	{Name: "stoplabeled", Type: "boolean", Description: "Stop the paths at the labeled entities"},
+}
+
+// pathQueryParams lists the most probable path query parameters. The chain
+// discovery query parameters apply too.
+var pathQueryParams = append([]v2Param{
+	{Name: "source", Type: "string", Description: "Source tx hash or address of the funds"},
+}, chainQueryParams...)
+
+// taintQueryParams lists the taint query parameters. The chain discovery query
+// parameters apply too.
+var taintQueryParams = append([]v2Param{
+	{Name: "policy", Type: "string", Description: "Taint policy, haircut (default), poison or fifo"},
+	{Name: "outpoint", Type: "string", Description: "Tainted outpoint as txhash:vout, repeatable"},
+	{Name: "address", Type: "string", Description: "Tainted address, repeatable"},
+}, chainQueryParams...)
+
+// explainQueryParam is the query parameter that requests the plain language
+// explanation of the analysis in the response meta data.
+var explainQueryParam = v2Param{Name: "explain", Type: "boolean",
+	Description: "Add the plain language explanation of the analysis to the meta data"}
+
+// v2Routes lists all the v2 API routes. It is set in init since the health
+// check handler lists the routes.
+var v2Routes []v2Route
+
+func init() {
+	v2Routes = []v2Route{
+		{
+			Method:  "GET",
+			Path:    "/api/v2",
+			Summary: "Checks if the system is up and running",
+			Data:    v2Health{},
+			handler: (*explorer).v2Health,
+		},
+		{
+			Method:  "POST",
+			Path:    "/api/v2/analyze",
+			Summary: "Funds flow analysis of a raw tx that does not need to be on chain",
+			Query:   []v2Param{explainQueryParam},
+			Body:    analyzeRequest{},
+			Data:    &analytics.TxAnalysis{},
+			handler: (*explorer).v2Analyze,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}",
+			Summary: "Funds flow analysis of the tx, the outputs probability and entropy included",
+			Query:   []v2Param{explainQueryParam},
+			Data:    &analytics.TxAnalysis{},
+			handler: (*explorer).v2Probability,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}/links",
+			Summary: "Probability of each tx input funding each tx output across all the solutions",
+			Data:    &analytics.LinkMatrix{},
+			handler: (*explorer).v2Links,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}/links/deterministic",
+			Summary: "Input to output links found in every funds flow solution of the tx",
+			Data:    []*analytics.DeterministicLink{},
+			handler: (*explorer).v2DeterministicLinks,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}/entropy",
+			Summary: "Interpretations count, entropy and determinism of the tx funds flow",
+			Data:    &analytics.TxEntropy{},
+			handler: (*explorer).v2Entropy,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}/privacy",
+			Summary: "Privacy score of each tx output and the factors that lowered it",
+			Query:   chainQueryParams,
+			Data:    []*analytics.PrivacyScore{},
+			handler: (*explorer).v2Privacy,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}/ticket",
+			Summary: "Commitments, participants and VSP fee of the ticket",
+			Data:    &analytics.TicketAnalysis{},
+			handler: (*explorer).v2Ticket,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}/solutions",
+			Summary: "All the raw funds flow solutions of the tx",
+			Query:   solutionsQueryParams,
+			Data:    &analytics.SolutionsPage{},
+			handler: (*explorer).v2Solutions,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}/chain",
+			Summary: "Funds flow paths of all the tx outputs",
+			Query:   chainQueryParams,
+			Data:    []*analytics.Hub{},
+			handler: (*explorer).v2Chain,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}/chain/{index:[0-9]+}",
+			Summary: "Funds flow path of the tx output at the index",
+			Query:   chainQueryParams,
+			Data:    []*analytics.Hub{},
+			handler: (*explorer).v2ChainPath,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}/taint/{index:[0-9]+}",
+			Summary: "Taint of the tx output at the index by the tainted outpoints and addresses",
+			Query:   taintQueryParams,
+			Data:    &analytics.TaintResult{},
+			handler: (*explorer).v2Taint,
+		},
+		{
+			Method:  "GET",
+			Path:    "/api/v2/tx/{tx}/path/{index:[0-9]+}",
+			Summary: "Most probable path from the tx output at the index to the source",
+			Query:   pathQueryParams,
+			Data:    &analytics.ProbablePath{},
+			handler: (*explorer).v2Path,
+		},
+	}
+}
+
+// registerV2Routes adds the v2 API routes and the OpenAPI document route to
+// the router.
+func registerV2Routes(r *mux.Router, exp *explorer) {
+	doc, err := json.Marshal(newOpenAPIDoc(v2Routes))
+	if err != nil {
+		// The document is generated from static types.
+		panic(err)
+	}
+
+	r.HandleFunc(openAPIPath, func(w http.ResponseWriter, r *http.Request) {
+		jsonWrite(doc, http.StatusOK, w)
+	}).Methods("GET")
+
+	for _, route := range v2Routes {
+		r.HandleFunc(route.Path, exp.v2Handler(route.handler)).Methods(route.Method)
+	}
+}
+
+// v2Handler returns the http handler that writes the v2 handler data or error
+// in the v2 response envelope.
+func (exp *explorer) v2Handler(fn v2HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t := time.Now()
+
+		data, meta, err := fn(exp, w, r)
+		meta.APIVersion = apiV2Version
+
+		status := http.StatusOK
+		resp := v2Envelope{Meta: meta}
+
+		if err != nil {
+			log.Error(err)
+
+			var code, msg string
+			status, code, msg = errorDetails(err)
+			resp.Meta.Explanation = nil
+			resp.Meta.Summary = nil
+			resp.Errors = []v2Error{{Code: code, Message: msg}}
+		} else {
+			resp.Data = data
+		}
+
+		resp.Meta.Duration = durationInSec(t)
+
+		byteData, err := json.Marshal(toV2(reflect.ValueOf(resp)))
+		if err != nil {
+			exp.StatusHandler(w, r, t, err)
+			return
+		}
+
+		jsonWrite(byteData, status, w)
+	}
+}
+
+// v2Health returns the v2 API health check payload.
+func (exp *explorer) v2Health(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	endpoints := []string{"GET " + openAPIPath}
+	for _, route := range v2Routes {
+		path := muxVarPattern.ReplaceAllString(route.Path, "{$1}")
+		endpoints = append(endpoints, route.Method+" "+path)
+	}
+	sort.Strings(endpoints)
+
+	return v2Health{Status: "ok", Endpoints: endpoints}, v2Meta{}, nil
+}
+
+// v2Analyze returns the funds flow analysis of the raw tx posted.
+func (exp *explorer) v2Analyze(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	analysis, err := exp.analyzeRawTx(w, r)
+	if err != nil {
+		return nil, v2Meta{}, err
+	}
+
+	// The explanation is returned in the meta data like the other routes.
+	meta := v2Meta{Explanation: analysis.Explanation}
+	analysis.Explanation = nil
+	return analysis, meta, nil
+}
+
+// v2Probability returns the funds flow analysis of the tx, like the raw tx
+// analysis, with the funds flow probability and the entropy of the tx outputs.
+func (exp *explorer) v2Probability(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	explain, err := explainRequested(r)
+	if err != nil {
+		return nil, v2Meta{}, err
+	}
+
+	analysis, txTime, err := exp.txAnalysis(mux.Vars(r)["tx"], true)
+	if err != nil {
+		return nil, v2Meta{}, err
+	}
+
+	meta := v2Meta{TxTime: txTime}
+	if explain {
+		meta.Explanation = analytics.ExplainAnalysis(analysis)
+	}
+	return analysis, meta, nil
+}
+
+// v2Links returns the probability of each tx input funding each tx output.
+func (exp *explorer) v2Links(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	analysis, txTime, err := exp.txAnalysis(mux.Vars(r)["tx"], false)
+	if err != nil {
+		return nil, v2Meta{}, err
+	}
+	return analysis.LinkMatrix, v2Meta{TxTime: txTime}, nil
+}
+
+// v2DeterministicLinks returns the input to output links found in all the tx
+// funds flow solutions.
+func (exp *explorer) v2DeterministicLinks(w http.ResponseWriter, r *http.Request) (
+	interface{}, v2Meta, error) {
+	analysis, txTime, err := exp.txAnalysis(mux.Vars(r)["tx"], false)
+	if err != nil {
+		return nil, v2Meta{}, err
+	}
+	return analysis.DeterministicLinks, v2Meta{TxTime: txTime}, nil
+}
+
+// v2Entropy returns the entropy metrics of the tx.
+func (exp *explorer) v2Entropy(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	analysis, txTime, err := exp.txAnalysis(mux.Vars(r)["tx"], false)
+	if err != nil {
+		return nil, v2Meta{}, err
+	}
+	return analysis.Entropy, v2Meta{TxTime: txTime}, nil
+}
+
+// v2Privacy returns the privacy scores of the tx outputs.
+func (exp *explorer) v2Privacy(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	scores, txTime, err := exp.txPrivacy(r)
+	return scores, v2Meta{TxTime: txTime}, err
+}
+
+// v2Ticket returns the ticket analysis of the tx.
+func (exp *explorer) v2Ticket(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	analysis, txTime, err := exp.txAnalysis(mux.Vars(r)["tx"], false)
+	if err != nil {
+		return nil, v2Meta{}, err
+	}
+
+	if analysis.Ticket == nil {
+		return nil, v2Meta{}, rpcutils.NewError(rpcutils.ErrInvalidRequest, "tx is not a ticket")
+	}
+	return analysis.Ticket, v2Meta{TxTime: txTime}, nil
+}
+
+// v2Solutions returns the page of the raw funds flow solutions of the tx that
+// matched the query parameters.
+func (exp *explorer) v2Solutions(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	explain, err := explainRequested(r)
+	if err != nil {
+		return nil, v2Meta{}, err
+	}
+
+	page, txTime, err := exp.querySolutions(r)
+	if err != nil {
+		return nil, v2Meta{}, err
+	}
+
+	meta := v2Meta{TxTime: txTime}
+	if explain {
+		meta.Explanation = analytics.ExplainSolutions(page.Solutions, page.Total)
+	}
+	return page, meta, nil
+}
+
+// v2Chain returns the funds flow paths of all the tx outputs.
+func (exp *explorer) v2Chain(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	hubs, txTime, err := exp.txChain(r)
+	return hubs, v2Meta{TxTime: txTime, Summary: analytics.ChainSources(hubs)}, err
+}
+
+// v2ChainPath returns the funds flow path of the tx output at the index.
+func (exp *explorer) v2ChainPath(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	txIndex, err := outputIndex(r)
+	if err != nil {
+		return nil, v2Meta{}, err
+	}
+
+	hubs, txTime, err := exp.txChain(r, txIndex)
+	return hubs, v2Meta{TxTime: txTime, Summary: analytics.ChainSources(hubs)}, err
+}
+
+// v2Path returns the most probable path from the tx output at the index to the
+// source.
+func (exp *explorer) v2Path(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	path, txTime, err := exp.txPath(r)
+	return path, v2Meta{TxTime: txTime}, err
+}
+
+// v2Taint returns the taint of the tx output at the index.
+func (exp *explorer) v2Taint(w http.ResponseWriter, r *http.Request) (interface{}, v2Meta, error) {
+	result, txTime, err := exp.txTaint(r)
+	return result, v2Meta{TxTime: txTime}, err
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// TestSnakeCase tests the conversion of the Go identifiers to snake case.
+func TestSnakeCase(t *testing.T) {
+	td := map[string]string{
+		"PathPercentOfInputs": "path_percent_of_inputs",
+		"TxID":                "tx_id",
+		"TxHash":              "tx_hash",
+		"APIVersion":          "api_version",
+		"Vout":                "vout",
+		"hex":                 "hex",
+		"Base58Addr":          "base58_addr",
+	}
+
+	for name, expected := range td {
+		t.Run(name, func(t *testing.T) {
+			if result := snakeCase(name); result != expected {
+				t.Fatalf("expected %s to be converted to %s but found %s", name,
+					expected, result)
+			}
+		})
+	}
+}
+
+// TestV2HandlersReplay runs the v2 API handlers against the recorded
+// transactions and compares their payloads with the golden files.
+func TestV2HandlersReplay(t *testing.T) {
+	type testData struct {
+		Name   string
+		Path   string
+		Status int
+	}
+
+	td := []testData{
+		{Name: "v2_health", Path: "/api/v2", Status: http.StatusOK},
+		{Name: "v2_probability", Path: "/api/v2/tx/" + replayTxID, Status: http.StatusOK},
+		{Name: "v2_probability_explain", Path: "/api/v2/tx/" + replayTxID + "?explain=true",
+			Status: http.StatusOK},
+		{Name: "v2_links", Path: "/api/v2/tx/" + replayTxID + "/links", Status: http.StatusOK},
+		{Name: "v2_deterministic_links", Status: http.StatusOK,
+			Path: "/api/v2/tx/" + replayTxID + "/links/deterministic"},
+		{Name: "v2_entropy", Path: "/api/v2/tx/" + replayTxID + "/entropy", Status: http.StatusOK},
+		{Name: "v2_privacy", Path: "/api/v2/tx/" + replayTxID + "/privacy?depth=2",
+			Status: http.StatusOK},
+		{Name: "v2_not_ticket", Path: "/api/v2/tx/" + replayTxID + "/ticket",
+			Status: http.StatusBadRequest},
+		{Name: "v2_solutions", Path: "/api/v2/tx/" + replayTxID + "/solutions",
+			Status: http.StatusOK},
+		{Name: "v2_chain_index", Path: "/api/v2/tx/" + replayTxID + "/chain/3",
+			Status: http.StatusOK},
+		{Name: "v2_taint", Status: http.StatusOK,
+			Path: "/api/v2/tx/" + replayTxID + "/taint/1?policy=poison" +
+				"&outpoint=932345119a257868a9c38ab51a0fce78c6ac3db331a04dc4603734d34a177014:0"},
+		{Name: "v2_path", Status: http.StatusOK,
+			Path: "/api/v2/tx/" + replayTxID + "/path/1" +
+				"?source=1bf3ac90ff68c46fa6d3a81509e885ab8149dbd329179854a1b720e427447c50"},
+		{Name: "v2_unknown_tx", Status: http.StatusNotFound,
+			Path: "/api/v2/tx/ae40333aed99c0b004ef01834444944c471bc72ce869949f6f8ced728941f561"},
+	}
+
+	exp := newTestExplorer()
+
+	for _, data := range td {
+		t.Run(data.Name, func(t *testing.T) {
+			w := serve(exp, "GET", data.Path, nil)
+			if w.Code != data.Status {
+				t.Fatalf("expected status %d but found %d: %s", data.Status, w.Code,
+					w.Body.String())
+			}
+
+			checkGolden(t, data.Name, w.Body.Bytes())
+		})
+	}
+}
+
+// TestOpenAPIDoc tests that the OpenAPI document describes all the v2 routes
+// and the schemas of the payloads they return.
+func TestOpenAPIDoc(t *testing.T) {
+	w := serve(newTestExplorer(), "GET", openAPIPath, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d but found %d", http.StatusOK, w.Code)
+	}
+
+	var doc struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("expected no error decoding the document but found %v", err)
+	}
+
+	if doc.OpenAPI != "3.0.0" {
+		t.Fatalf("expected the OpenAPI version 3.0.0 but found %s", doc.OpenAPI)
+	}
+
+	paths := []string{"/api/v2", "/api/v2/analyze", "/api/v2/tx/{tx}", "/api/v2/tx/{tx}/links",
+		"/api/v2/tx/{tx}/links/deterministic", "/api/v2/tx/{tx}/entropy", "/api/v2/tx/{tx}/privacy", "/api/v2/tx/{tx}/ticket", "/api/v2/tx/{tx}/solutions", "/api/v2/tx/{tx}/chain", "/api/v2/tx/{tx}/chain/{index}",
+		"/api/v2/tx/{tx}/taint/{index}", "/api/v2/tx/{tx}/path/{index}"}
+
+	for i, path := range paths {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			if len(doc.Paths[path]) == 0 {
+				t.Fatalf("expected the %s path to be documented but found none", path)
+			}
+		})
+	}
+
+	hub, ok := doc.Components.Schemas["Hub"]
+	if !ok {
+		t.Fatal("expected the Hub schema to be documented but found none")
+	}
+
+	for _, field := range []string{"tx_hash", "path_probability", "matched"} {
+		if _, ok := hub.Properties[field]; !ok {
+			t.Fatalf("expected the Hub schema to have the %s property but found none", field)
+		}
+	}
+}
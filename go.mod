@@ -1,34 +1,63 @@
 module github.com/raedahgroup/dcrchainanalysis/v1
 
+go 1.27.1
+
 require (
 	github.com/btcsuite/btclog v0.0.0-20170628155309-84c8d2346e9f
-	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/decred/dcrd/blockchain/stake v1.0.2
 	github.com/decred/dcrd/chaincfg v1.1.1
 	github.com/decred/dcrd/chaincfg/chainhash v1.0.1
-	github.com/decred/dcrd/database v1.0.2 // indirect
-	github.com/decred/dcrd/dcrec v0.0.0-20180817010327-36f61d8ebd7a // indirect
-	github.com/decred/dcrd/dcrec/edwards v0.0.0-20180817010327-36f61d8ebd7a // indirect
 	github.com/decred/dcrd/dcrjson v1.0.0
 	github.com/decred/dcrd/dcrutil v1.1.1
-	github.com/decred/dcrd/gcs v1.0.2 // indirect
 	github.com/decred/dcrd/rpcclient v1.0.2
 	github.com/decred/dcrd/txscript v1.0.1
 	github.com/decred/dcrd/wire v1.1.0
 	github.com/decred/dcrwallet/version v1.0.0
 	github.com/decred/slog v1.0.0
+	github.com/gorilla/mux v1.6.2
+	github.com/jessevdk/go-flags v1.4.0
+	github.com/jrick/logrotate v1.0.0
+	github.com/prometheus/client_golang v0.9.2
+)
+
+require (
+	github.com/aead/siphash v0.0.0-20170329201724-e404fcfc8885 // indirect
+	github.com/agl/ed25519 v0.0.0-20170116200512-5312a6153412 // indirect
+	github.com/beorn7/perks v0.0.0-20180321164747-3a771d992973 // indirect
+	github.com/btcsuite/go-socks v0.0.0-20170105172521-4720035b7bfd // indirect
+	github.com/btcsuite/goleveldb v1.0.0 // indirect
+	github.com/btcsuite/snappy-go v1.0.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dchest/blake256 v1.0.0 // indirect
+	github.com/dchest/siphash v1.2.0 // indirect
+	github.com/decred/base58 v1.0.0 // indirect
+	github.com/decred/dcrd/blockchain v1.0.1 // indirect
+	github.com/decred/dcrd/database v1.0.2 // indirect
+	github.com/decred/dcrd/dcrec v0.0.0-20180817010327-36f61d8ebd7a // indirect
+	github.com/decred/dcrd/dcrec/edwards v0.0.0-20180817010327-36f61d8ebd7a // indirect
+	github.com/decred/dcrd/dcrec/secp256k1 v1.0.0 // indirect
+	github.com/decred/dcrd/gcs v1.0.2 // indirect
 	github.com/fsnotify/fsnotify v1.4.7 // indirect
 	github.com/golang/protobuf v1.2.0 // indirect
 	github.com/gorilla/context v1.1.1 // indirect
-	github.com/gorilla/mux v1.6.2
+	github.com/gorilla/websocket v1.2.0 // indirect
 	github.com/hpcloud/tail v1.0.0 // indirect
-	github.com/jessevdk/go-flags v1.4.0
-	github.com/jrick/logrotate v1.0.0
 	github.com/kr/pretty v0.1.0 // indirect
+	github.com/kr/pty v1.1.1 // indirect
+	github.com/kr/text v0.1.0 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/onsi/ginkgo v1.6.0 // indirect
+	github.com/onsi/gomega v1.4.1 // indirect
+	github.com/prometheus/client_model v0.0.0-20180712105110-5c3871d89910 // indirect
+	github.com/prometheus/common v0.0.0-20181126121408-4724e9255275 // indirect
+	github.com/prometheus/procfs v0.0.0-20181204211112-1dc9a6cbc91a // indirect
 	golang.org/x/crypto v0.0.0-20180820150726-614d502a4dac // indirect
-	golang.org/x/net v0.0.0-20180821023952-922f4815f713 // indirect
-	golang.org/x/sync v0.0.0-20180314180146-1d60e4601c6f // indirect
+	golang.org/x/net v0.0.0-20181201002055-351d144fa1fc // indirect
+	golang.org/x/sync v0.0.0-20181108010431-42b317875d0f // indirect
 	golang.org/x/sys v0.0.0-20180821140842-3b58ed4ad339 // indirect
+	golang.org/x/text v0.3.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 	gopkg.in/fsnotify.v1 v1.4.7 // indirect
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
+	gopkg.in/yaml.v2 v2.2.1 // indirect
 )
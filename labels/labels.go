@@ -0,0 +1,362 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+// Package labels manages the entity labels of known addresses such as the
+// exchanges, VSPs, mining pools and projects addresses.
+package labels
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// Category defines the kind of entity an address belongs to.
+type Category string
+
+const (
+	// CategoryExchange labels the exchanges addresses.
+	CategoryExchange Category = "exchange"
+
+	// CategoryVSP labels the voting service providers addresses.
+	CategoryVSP Category = "vsp"
+
+	// CategoryPool labels the mining pools addresses.
+	CategoryPool Category = "pool"
+
+	// CategoryProject labels the projects addresses e.g. the treasury.
+	CategoryProject Category = "project"
+
+	// CategoryOther labels the addresses of the other known entities.
+	CategoryOther Category = "other"
+)
+
+// adminFile is the labels file managed through the admin API. Its labels take
+// precedence over the labels of the other files.
+const adminFile = "labels.json"
+
+// Label is the entity label of an address.
+type Label struct {
+	Address  string
+	Entity   string
+	Category Category
+}
+
+// Store holds the labels loaded from the CSV and JSON files of a directory.
+// The labels set through the admin API are saved to the labels.json file of
+// the directory. The labels of the other files can only be changed by editing
+// the files. The Store is safe for concurrent access and its lookups are safe
+// on a nil Store.
+type Store struct {
+	mtx   sync.RWMutex
+	dir   string
+	files map[string]*Label
+	admin map[string]*Label
+}
+
+// NewStore returns a Store with the labels of all the CSV and JSON files in
+// dir. The CSV files rows hold the address, the entity and the category with an
+// optional header row. The JSON files hold an array of labels. An empty Store
+// is returned if dir does not exist.
+func NewStore(dir string) (*Store, error) {
+	s := &Store{
+		dir:   dir,
+		files: make(map[string]*Label),
+		admin: make(map[string]*Label),
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the labels directory %s: %v", dir, err)
+	}
+
+	for _, info := range infos {
+		name := info.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if info.IsDir() || (ext != ".csv" && ext != ".json") {
+			continue
+		}
+
+		labels, err := readFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		dest := s.files
+		if name == adminFile {
+			dest = s.admin
+		}
+
+		for _, l := range labels {
+			dest[l.Address] = l
+		}
+	}
+
+	return s, nil
+}
+
+// readFile reads and validates the labels of a CSV or JSON file.
+func readFile(path string) ([]*Label, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the labels file %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var labels []*Label
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		labels, err = readCSV(f)
+	} else {
+		err = json.NewDecoder(f).Decode(&labels)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the labels file %s: %v", path, err)
+	}
+
+	for _, l := range labels {
+		if err = l.validate(); err != nil {
+			return nil, fmt.Errorf("invalid label in %s: %v", path, err)
+		}
+	}
+
+	return labels, nil
+}
+
+// readCSV reads the address, entity and category rows. The first row is
+// skipped if it is a header row.
+func readCSV(r io.Reader) ([]*Label, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) > 0 && strings.EqualFold(rows[0][0], "address") {
+		rows = rows[1:]
+	}
+
+	labels := make([]*Label, 0, len(rows))
+	for _, row := range rows {
+		labels = append(labels, &Label{
+			Address:  strings.TrimSpace(row[0]),
+			Entity:   strings.TrimSpace(row[1]),
+			Category: Category(strings.ToLower(strings.TrimSpace(row[2]))),
+		})
+	}
+	return labels, nil
+}
+
+// validate checks that the label fields are set and that the category is
+// known.
+func (l *Label) validate() error {
+	if l.Address == "" || strings.ContainsAny(l.Address, " \t\n,") {
+		return fmt.Errorf("invalid address %q", l.Address)
+	}
+
+	if l.Entity == "" {
+		return fmt.Errorf("missing entity of address %s", l.Address)
+	}
+
+	switch l.Category {
+	case CategoryExchange, CategoryVSP, CategoryPool, CategoryProject, CategoryOther:
+	default:
+		return fmt.Errorf("invalid category %q of address %s: expected %s, %s, %s, %s or %s",
+			l.Category, l.Address, CategoryExchange, CategoryVSP, CategoryPool,
+			CategoryProject, CategoryOther)
+	}
+	return nil
+}
+
+// get returns the label of the address. The caller should hold the mutex.
+func (s *Store) get(address string) (*Label, bool) {
+	if l, ok := s.admin[address]; ok {
+		return l, true
+	}
+
+	l, ok := s.files[address]
+	return l, ok
+}
+
+// Get returns a copy of the label of the address. An ErrLabelNotFound error is
+// returned if the address is not labeled.
+func (s *Store) Get(address string) (*Label, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	l, ok := s.get(address)
+	if !ok {
+		return nil, rpcutils.NewError(rpcutils.ErrLabelNotFound,
+			fmt.Sprintf("no label is set for address %s", address))
+	}
+
+	label := *l
+	return &label, nil
+}
+
+// Lookup returns the labels of the addresses that are labeled.
+func (s *Store) Lookup(addresses []string) []*Label {
+	if s == nil {
+		return nil
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	var labels []*Label
+	for _, addr := range addresses {
+		if l, ok := s.get(addr); ok {
+			label := *l
+			labels = append(labels, &label)
+		}
+	}
+	return labels
+}
+
+// Len returns the number of addresses labeled.
+func (s *Store) Len() int {
+	if s == nil {
+		return 0
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	n := len(s.admin)
+	for addr := range s.files {
+		if _, ok := s.admin[addr]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// List returns copies of all the labels sorted by address.
+func (s *Store) List() []*Label {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	labels := make([]*Label, 0, len(s.admin)+len(s.files))
+	for addr, l := range s.files {
+		if _, ok := s.admin[addr]; !ok {
+			label := *l
+			labels = append(labels, &label)
+		}
+	}
+
+	for _, l := range s.admin {
+		label := *l
+		labels = append(labels, &label)
+	}
+
+	sortByAddress(labels)
+	return labels
+}
+
+// Put adds or replaces the label of the address and saves the labels set
+// through the admin API. An ErrInvalidRequest error is returned if the label
+// is invalid.
+func (s *Store) Put(label Label) error {
+	if err := label.validate(); err != nil {
+		return rpcutils.NewError(rpcutils.ErrInvalidRequest, err.Error())
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	prev, ok := s.admin[label.Address]
+	s.admin[label.Address] = &label
+
+	if err := s.save(); err != nil {
+		if ok {
+			s.admin[label.Address] = prev
+		} else {
+			delete(s.admin, label.Address)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the label of the address set through the admin API. An
+// ErrLabelNotFound error is returned if the address is not labeled and an
+// ErrInvalidRequest error if the label was loaded from another file.
+func (s *Store) Delete(address string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	prev, ok := s.admin[address]
+	if !ok {
+		if _, ok = s.files[address]; ok {
+			return rpcutils.NewError(rpcutils.ErrInvalidRequest,
+				fmt.Sprintf("the label of address %s is set in a labels file of %s "+
+					"and can only be removed from the file", address, s.dir))
+		}
+
+		return rpcutils.NewError(rpcutils.ErrLabelNotFound,
+			fmt.Sprintf("no label is set for address %s", address))
+	}
+
+	delete(s.admin, address)
+
+	if err := s.save(); err != nil {
+		s.admin[address] = prev
+		return err
+	}
+	return nil
+}
+
+// save writes the labels set through the admin API to the labels.json file.
+// The caller should hold the mutex.
+func (s *Store) save() error {
+	labels := make([]*Label, 0, len(s.admin))
+	for _, l := range s.admin {
+		labels = append(labels, l)
+	}
+	sortByAddress(labels)
+
+	data, err := json.MarshalIndent(labels, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create the labels directory %s: %v", s.dir, err)
+	}
+
+	// Write to a temporary file first so that a failed write does not leave a
+	// truncated labels file.
+	path := filepath.Join(s.dir, adminFile)
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to save the labels to %s: %v", path, err)
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save the labels to %s: %v", path, err)
+	}
+	return nil
+}
+
+// sortByAddress sorts the labels by address.
+func sortByAddress(labels []*Label) {
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Address < labels[j].Address
+	})
+}
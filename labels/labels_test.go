@@ -0,0 +1,172 @@
+package labels
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// writeFiles creates a temporary labels directory holding the files provided.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "labels")
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	for name, contents := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+	}
+	return dir
+}
+
+// TestNewStore tests loading the labels from the CSV and JSON files.
+func TestNewStore(t *testing.T) {
+	type testData struct {
+		Files       map[string]string
+		Expected    []*Label
+		ExpectedErr bool
+	}
+
+	td := []testData{
+		{
+			Files: map[string]string{
+				"exchanges.csv": "address,entity,category\nTsA, Exchange A, Exchange\nTsB,Exchange B,exchange\n",
+				"pools.json":    `[{"Address":"TsC","Entity":"Pool C","Category":"pool"}]`,
+				"labels.json":   `[{"Address":"TsB","Entity":"VSP B","Category":"vsp"}]`,
+				"notes.txt":     "ignored",
+			},
+			Expected: []*Label{
+				{Address: "TsA", Entity: "Exchange A", Category: CategoryExchange},
+				{Address: "TsB", Entity: "VSP B", Category: CategoryVSP},
+				{Address: "TsC", Entity: "Pool C", Category: CategoryPool},
+			},
+		},
+		{
+			Files:    map[string]string{},
+			Expected: []*Label{},
+		},
+		{
+			Files:       map[string]string{"bad.csv": "TsA,Exchange A,bank\n"},
+			ExpectedErr: true,
+		},
+		{
+			Files:       map[string]string{"bad.csv": "TsA,Exchange A\n"},
+			ExpectedErr: true,
+		},
+		{
+			Files:       map[string]string{"bad.json": `[{"Address":"TsA","Category":"pool"}]`},
+			ExpectedErr: true,
+		},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			dir := writeFiles(t, data.Files)
+			defer os.RemoveAll(dir)
+
+			s, err := NewStore(dir)
+			if data.ExpectedErr {
+				if err == nil {
+					t.Fatal("expected an error but found none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error but found: %v", err)
+			}
+
+			if list := s.List(); !reflect.DeepEqual(list, data.Expected) {
+				t.Fatalf("expected labels %+v but found %+v", data.Expected, list)
+			}
+
+			if s.Len() != len(data.Expected) {
+				t.Fatalf("expected %d labels but found %d", len(data.Expected), s.Len())
+			}
+		})
+	}
+}
+
+// TestStoreUpdates tests that the labels set and removed are saved and that
+// the labels of the other files cannot be removed.
+func TestStoreUpdates(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"vsps.csv": "TsV,VSP V,vsp\n"})
+	defer os.RemoveAll(dir)
+
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	err = s.Put(Label{Address: "TsA", Entity: "Exchange A", Category: "bank"})
+	if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != rpcutils.ErrInvalidRequest {
+		t.Fatalf("expected a %v error but found %v", rpcutils.ErrInvalidRequest, err)
+	}
+
+	for _, l := range []Label{
+		{Address: "TsA", Entity: "Exchange A", Category: CategoryExchange},
+		{Address: "TsB", Entity: "Project B", Category: CategoryProject},
+		{Address: "TsV", Entity: "VSP W", Category: CategoryVSP},
+	} {
+		if err = s.Put(l); err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+	}
+
+	if err = s.Delete("TsB"); err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	err = s.Delete("TsB")
+	if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != rpcutils.ErrLabelNotFound {
+		t.Fatalf("expected a %v error but found %v", rpcutils.ErrLabelNotFound, err)
+	}
+
+	// Removing the admin label of TsV reveals the label of its file which
+	// cannot be removed.
+	if err = s.Delete("TsV"); err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	err = s.Delete("TsV")
+	if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != rpcutils.ErrInvalidRequest {
+		t.Fatalf("expected a %v error but found %v", rpcutils.ErrInvalidRequest, err)
+	}
+
+	reloaded, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	expected := []*Label{
+		{Address: "TsA", Entity: "Exchange A", Category: CategoryExchange},
+		{Address: "TsV", Entity: "VSP V", Category: CategoryVSP},
+	}
+
+	if list := reloaded.List(); !reflect.DeepEqual(list, expected) {
+		t.Fatalf("expected labels %+v but found %+v", expected, list)
+	}
+
+	lookup := reloaded.Lookup([]string{"TsX", "TsA"})
+	if !reflect.DeepEqual(lookup, expected[:1]) {
+		t.Fatalf("expected labels %+v but found %+v", expected[:1], lookup)
+	}
+
+	_, err = reloaded.Get("TsB")
+	if code, ok := rpcutils.ErrorCodeOf(err); !ok || code != rpcutils.ErrLabelNotFound {
+		t.Fatalf("expected a %v error but found %v", rpcutils.ErrLabelNotFound, err)
+	}
+
+	var nilStore *Store
+	if nilStore.Len() != 0 || nilStore.Lookup([]string{"TsA"}) != nil {
+		t.Fatal("expected a nil store to hold no labels")
+	}
+}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/jrick/logrotate/rotator"
 	"github.com/raedahgroup/dcrchainanalysis/v1/analytics"
 	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+	"github.com/raedahgroup/dcrchainanalysis/v1/watchlist"
 )
 
 //
@@ -36,12 +38,16 @@ import (
 // 				 active by default.
 //
 
-// logWriter implements an io.Writer that outputs to both standard output and
+// logStdout is the console output of the logs. It defaults to the standard
+// output.
+var logStdout io.Writer = os.Stdout
+
+// logWriter implements an io.Writer that outputs to both the console output and
 // the write-end pipe of an initialized log rotator.
 type logWriter struct{}
 
 func (logWriter) Write(p []byte) (n int, err error) {
-	os.Stdout.Write(p)
+	logStdout.Write(p)
 	logRotator.Write(p)
 	return len(p), nil
 }
@@ -66,6 +72,7 @@ var (
 
 	analyticsLog = backendLog.Logger("DCA-ANLY")
 	rpcutilsLog  = backendLog.Logger("DCA-RPC")
+	watchlistLog = backendLog.Logger("DCA-WTCH")
 	log          = backendLog.Logger("DCA-NFTN")
 )
 
@@ -73,6 +80,7 @@ var (
 func init() {
 	rpcutils.UseLogger(rpcutilsLog)
 	analytics.UseLogger(analyticsLog)
+	watchlist.UseLogger(watchlistLog)
 }
 
 // subsystemLoggers maps each subsystem identifier to its associated logger.
@@ -80,6 +88,7 @@ var subsystemLoggers = map[string]slog.Logger{
 	"DCA-RPC":  rpcutilsLog,
 	"DCA-NFTN": log,
 	"DCA-ANLY": analyticsLog,
+	"DCA-WTCH": watchlistLog,
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
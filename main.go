@@ -3,13 +3,20 @@
 package main
 
 import (
+	"crypto/tls"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
+	"github.com/decred/dcrd/rpcclient"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/raedahgroup/dcrchainanalysis/v1/labels"
 	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+	"github.com/raedahgroup/dcrchainanalysis/v1/watchlist"
 )
 
 // start sets up the explorer.
@@ -21,39 +28,132 @@ func start() (*explorer, error) {
 
 	log.Info("Starting up the Chain Analysis Tool")
 
-	client, rpcVersion, err := rpcutils.ConnectRPCNode(cfg.DcrdServ, cfg.DcrdUser,
-		cfg.DcrdPass, cfg.DcrdCert, cfg.DisableDaemonTLS, nil)
+	exp := &explorer{
+		Params:      cfg,
+		OtherParams: otherCfg,
+	}
+
+	if cfg.MaxAnalyses > 0 {
+		exp.Analyses = make(chan struct{}, cfg.MaxAnalyses)
+	}
+
+	switch {
+	case cfg.ReplayDir != "":
+		exp.Client = rpcutils.NewReplayer(cfg.ReplayDir)
+
+		log.Infof("Replaying the transactions recorded in %s", cfg.ReplayDir)
+
+	case cfg.TxSource == txSourceDcrdata:
+		exp.Client = rpcutils.NewDcrdataClient(cfg.DcrdataURL)
+
+		log.Infof("Using the dcrdata instance at %s as the transactions source: %s",
+			cfg.DcrdataURL, otherCfg.ActiveNet.String())
+
+	default:
+		pool, err := rpcutils.NewNodePool(otherCfg.Backends)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Infof("Connected to %d of %d dcrd node(s) successfully: %s, %s",
+			pool.Connected(), pool.Size(), otherCfg.ActiveNet.String(),
+			pool.Version().String())
+
+		exp.Client = pool
+		exp.Nodes = pool
+		exp.RPCVersion = pool.Version()
+	}
+
+	if cfg.RecordDir != "" {
+		exp.Client, err = rpcutils.NewRecorder(exp.Client, cfg.RecordDir)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Infof("Recording the transactions fetched into %s", cfg.RecordDir)
+	}
+
+	exp.Labels, err = labels.NewStore(cfg.LabelsDir)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Infof("Connected to a dcrd node successfully: %s, %s",
-		otherCfg.ActiveNet.String(), rpcVersion.String())
+	log.Infof("Loaded %d address label(s) from %s", exp.Labels.Len(), cfg.LabelsDir)
 
-	exp := &explorer{
-		Client:      client,
-		RPCVersion:  rpcVersion,
-		Params:      cfg,
-		OtherParams: otherCfg,
+	exp.Auth = newAPIAuth(cfg)
+	if len(cfg.APIKeys) > 0 {
+		log.Infof("API key authentication enabled for %d key(s)", len(cfg.APIKeys))
+	}
+
+	if len(cfg.AdminKeys) > 0 {
+		log.Infof("Admin API enabled for %d key(s)", len(cfg.AdminKeys))
 	}
 
 	return exp, nil
 }
 
-// main initaites program execution.
-func main() {
-	expl, err := start()
+// startWatcher sets up the watchlist and feeds it the blocks connected by the
+// first dcrd backend. The blocks are fetched from the dcrd backends pool. The
+// first backend must be up since it is the only one notifying the new blocks.
+// The returned notification client must be shut down once the watcher stops.
+func startWatcher(exp *explorer) (client *rpcclient.Client, err error) {
+	cfg := exp.Params
+	exp.Watcher, err = watchlist.New(watchlist.Config{
+		Addresses:      cfg.WatchAddresses,
+		Outpoints:      cfg.WatchOutpoints,
+		MinProbability: cfg.WatchProbability,
+		AlertsFile:     cfg.AlertsFile,
+		Webhooks:       cfg.Webhooks,
+		Retries:        cfg.WebhookRetries,
+		RetryInterval:  cfg.WebhookRetryInterval,
+		ActiveNet:      exp.OtherParams.ActiveNet,
+	}, exp.Client)
 	if err != nil {
-		log.Error(err)
-		os.Exit(1)
+		return nil, err
+	}
+
+	backend := exp.OtherParams.Backends[0]
+	client, _, err = rpcutils.ConnectRPCNode(backend.Host, backend.User, backend.Pass,
+		backend.Cert, backend.DisableTLS, &rpcclient.NotificationHandlers{
+			OnBlockConnected: exp.Watcher.BlockConnected,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("the watchlist needs the first dcrd backend %s "+
+			"for the new blocks notifications: %v", backend.Host, err)
 	}
 
+	if err = client.NotifyBlocks(); err != nil {
+		client.Shutdown()
+		return nil, fmt.Errorf("failed to subscribe to the new blocks: %v", err)
+	}
+
+	exp.Watcher.Start(exp.Nodes)
+
+	log.Infof("Watching %d address(es) and %d outpoint(s) with %d webhook(s)",
+		len(cfg.WatchAddresses), len(cfg.WatchOutpoints), len(cfg.Webhooks))
+
+	return client, nil
+}
+
+// newRouter sets up the routes served by the explorer.
+func newRouter(expl *explorer) *mux.Router {
 	r := mux.NewRouter()
+	r.Use(metricsMiddleware, expl.Auth.middleware)
 	r.HandleFunc("/", expl.HealthHandler)
+	r.Handle("/metrics", promhttp.Handler())
+	r.HandleFunc("/api/v1/analyze", expl.AnalyzeHandler).Methods("POST")
+	r.HandleFunc("/api/v1/alerts", expl.AlertsHandler)
+	r.HandleFunc("/api/v1/common-ancestry", expl.CommonAncestryHandler)
+	registerV2Routes(r, expl)
 	r.HandleFunc("/api/v1/{tx}", expl.TxProbabilityHandler)
 	r.HandleFunc("/api/v1/{tx}/all", expl.AllTxSolutionsHandler)
 	r.HandleFunc("/api/v1/{tx}/chain", expl.ChainHandler)
 	r.HandleFunc("/api/v1/{tx}/chain/{index:[0-9]+}", expl.ChainPathHandler)
+	r.HandleFunc("/api/v1/{tx}/taint/{index:[0-9]+}", expl.TaintHandler)
+	r.HandleFunc("/api/v1/{tx}/path/{index:[0-9]+}", expl.PathHandler)
+	r.HandleFunc("/api/v1/admin/labels", expl.LabelsHandler).Methods("GET")
+	r.HandleFunc("/api/v1/admin/labels/{address}", expl.LabelHandler).
+		Methods("GET", "PUT", "DELETE")
 
 	if expl.Params.CPUProfile {
 		log.Debug("CPU profiling Activated. Setting up the Profiling.")
@@ -63,6 +163,33 @@ func main() {
 	// Return the health page for all 404s
 	r.NotFoundHandler = http.HandlerFunc(expl.HealthHandler)
 
+	return r
+}
+
+// main initaites program execution.
+func main() {
+	expl, err := start()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	// Run the one-shot analysis subcommand if one was provided instead of
+	// starting the server.
+	if args := expl.OtherParams.RemainingArgs; len(args) > 0 {
+		os.Exit(runCommand(expl, args, os.Stdout, os.Stderr))
+	}
+
+	var notifier *rpcclient.Client
+	if expl.Params.watchEnabled() {
+		if notifier, err = startWatcher(expl); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+	}
+
+	r := newRouter(expl)
+
 	server := &http.Server{
 		Handler:      r,
 		Addr:         expl.Params.DCAHost,
@@ -70,15 +197,40 @@ func main() {
 		ReadTimeout:  30 * time.Second,
 	}
 
-	log.Info("Server running :", expl.Params.DCAHost)
-
-	// start server in a go routine.
-	go func() {
-		if err = server.ListenAndServe(); err != nil {
-			log.Error(err)
-			os.Exit(1)
+	if expl.Params.NoTLS {
+		log.Info("Server running : http://", expl.Params.DCAHost)
+
+		// start server in a go routine.
+		go func() {
+			if err = server.ListenAndServe(); err != nil {
+				log.Error(err)
+				os.Exit(1)
+			}
+		}()
+	} else {
+		certFile, keyFile := expl.Params.TLSCert, expl.Params.TLSKey
+
+		// Generate the self-signed TLS cert and key if neither exist.
+		if !fileExists(certFile) && !fileExists(keyFile) {
+			host, _, _ := net.SplitHostPort(expl.Params.DCAHost)
+			if err = genCertPair(certFile, keyFile, host); err != nil {
+				log.Errorf("Unable to generate the TLS cert and key: %v", err)
+				os.Exit(1)
+			}
 		}
-	}()
+
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+		log.Info("Server running : https://", expl.Params.DCAHost)
+
+		// start server in a go routine.
+		go func() {
+			if err = server.ListenAndServeTLS(certFile, keyFile); err != nil {
+				log.Error(err)
+				os.Exit(1)
+			}
+		}()
+	}
 
 	c := make(chan os.Signal, 1)
 	defer close(c)
@@ -91,6 +243,12 @@ func main() {
 	<-c
 
 	log.Info("(Ctrl+C) pressed")
+
+	if expl.Watcher != nil {
+		notifier.Shutdown()
+		expl.Watcher.Stop()
+	}
+
 	log.Info("Bye, System shutting down")
 	os.Exit(0)
 }
@@ -0,0 +1,59 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// httpDuration tracks the latency of the HTTP requests per route.
+var httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "dca",
+	Subsystem: "http",
+	Name:      "request_duration_seconds",
+	Help:      "Latency of the HTTP requests.",
+	Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+}, []string{"route", "method", "code"})
+
+func init() {
+	prometheus.MustRegister(httpDuration)
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code before writing it.
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// metricsMiddleware records the latency of the requests served by the matched
+// route. The route path template is used as the label so that the requests
+// for different txs are grouped together.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		httpDuration.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).
+			Observe(time.Since(t).Seconds())
+	})
+}
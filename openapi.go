@@ -0,0 +1,346 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// muxVarPattern matches the regular expression part of the mux path
+	// variables.
+	muxVarPattern = regexp.MustCompile(`\{([^}:]+):[^}]+\}`)
+
+	// pathVarPattern matches the names of the path variables.
+	pathVarPattern = regexp.MustCompile(`\{([^}:]+)`)
+)
+
+// snakeCase converts a Go identifier such as PathPercentOfInputs or TxID into
+// its snake case form i.e. path_percent_of_inputs and tx_id.
+func snakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// v2Field defines how a struct field is encoded by the v2 API.
+type v2Field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	typ       reflect.Type
+}
+
+// v2Fields returns the fields of the struct type encoded by the v2 API. The
+// fields are named in snake case after their JSON name or their Go name. The
+// fields of the embedded structs are promoted like encoding/json does.
+func v2Fields(t reflect.Type) []v2Field {
+	var fields []v2Field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			for _, embedded := range v2Fields(f.Type) {
+				embedded.index = append([]int{i}, embedded.index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+
+		// Skip the unexported fields.
+		if f.PkgPath != "" {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		name := f.Name
+		if opts[0] != "" {
+			name = opts[0]
+		}
+
+		field := v2Field{name: snakeCase(name), index: []int{i}, typ: f.Type}
+		for _, opt := range opts[1:] {
+			if opt == "omitempty" {
+				field.omitEmpty = true
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// toV2 converts the value into the generic maps and slices encoded by the v2
+// API with all the struct fields in snake case.
+func toV2(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toV2(v.Elem())
+
+	case reflect.Struct:
+		data := make(map[string]interface{})
+		for _, f := range v2Fields(v.Type()) {
+			fv := v.FieldByIndex(f.index)
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			data[f.name] = toV2(fv)
+		}
+		return data
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+
+		data := make([]interface{}, v.Len())
+		for i := range data {
+			data[i] = toV2(v.Index(i))
+		}
+		return data
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+
+		data := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			data[fmt.Sprint(key.Interface())] = toV2(v.MapIndex(key))
+		}
+		return data
+	}
+
+	return v.Interface()
+}
+
+// isEmptyValue checks if the value is empty as defined by the encoding/json
+// omitempty option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// openAPISpec generates the OpenAPI 3 document of the v2 routes. The schemas
+// are generated from the Go types returned so that they always match the
+// payloads.
+type openAPISpec struct {
+	schemas map[string]interface{}
+}
+
+// schemaRef returns the reference to a component schema.
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// schema returns the JSON schema of the type. Named struct types are added to
+// the component schemas and referenced so that recursive types are supported.
+func (s *openAPISpec) schema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.schema(t.Elem())
+
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": s.schema(t.Elem()),
+		}
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+
+		if _, ok := s.schemas[t.Name()]; !ok {
+			// Reserve the name first since the struct may reference itself.
+			s.schemas[t.Name()] = nil
+			s.schemas[t.Name()] = s.object(t)
+		}
+		return schemaRef(t.Name())
+	}
+
+	return map[string]interface{}{}
+}
+
+// object returns the JSON schema of the struct type fields.
+func (s *openAPISpec) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	for _, f := range v2Fields(t) {
+		properties[f.name] = s.schema(f.typ)
+		if !f.omitEmpty {
+			required = append(required, f.name)
+		}
+	}
+
+	obj := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+// envelope returns the schema of the v2 response envelope with the data type
+// provided.
+func (s *openAPISpec) envelope(data interface{}) map[string]interface{} {
+	properties := map[string]interface{}{
+		"meta":   s.schema(reflect.TypeOf(v2Meta{})),
+		"errors": s.schema(reflect.TypeOf([]v2Error{})),
+	}
+
+	if data != nil {
+		properties["data"] = s.schema(reflect.TypeOf(data))
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"meta"},
+	}
+}
+
+// operation returns the OpenAPI operation of the route.
+func (s *openAPISpec) operation(route v2Route) map[string]interface{} {
+	var params []interface{}
+	for _, match := range pathVarPattern.FindAllStringSubmatch(route.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+
+	for _, p := range route.Query {
+		params = append(params, map[string]interface{}{
+			"name":        p.Name,
+			"in":          "query",
+			"description": p.Description,
+			"schema":      map[string]interface{}{"type": p.Type},
+		})
+	}
+
+	op := map[string]interface{}{
+		"summary": route.Summary,
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "Success",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": s.envelope(route.Data),
+					},
+				},
+			},
+			"default": map[string]interface{}{
+				"description": "Error",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": s.envelope(nil),
+					},
+				},
+			},
+		},
+	}
+
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if route.Body != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": s.schema(reflect.TypeOf(route.Body)),
+				},
+			},
+		}
+	}
+	return op
+}
+
+// newOpenAPIDoc generates the OpenAPI 3 document of the routes provided.
+func newOpenAPIDoc(routes []v2Route) map[string]interface{} {
+	spec := &openAPISpec{schemas: make(map[string]interface{})}
+
+	paths := make(map[string]interface{})
+	for _, route := range routes {
+		path := muxVarPattern.ReplaceAllString(route.Path, "{$1}")
+
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = spec.operation(route)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":       "Decred Chain Analysis Tool API",
+			"version":     apiV2Version,
+			"description": "Funds flow analysis of the Decred transactions.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": spec.schemas,
+		},
+	}
+}
@@ -2,6 +2,12 @@
 // See LICENSE for details.
 package rpcutils
 
+import (
+	"math"
+
+	"github.com/decred/dcrd/txscript"
+)
+
 // Block describes part of the block information trimmed from the block data
 // by the rpc client.
 type Block struct {
@@ -16,7 +22,8 @@ type Block struct {
 }
 
 // Transaction holds generic block transaction data that contains both the
-// transaction's input or output data.
+// transaction's input or output data. Size is the serialized transaction size
+// in bytes, zero if unknown.
 type Transaction struct {
 	BlockTime   int64
 	TxID        string
@@ -25,12 +32,22 @@ type Transaction struct {
 	Spent       float64
 	Sent        float64
 	Fees        float64
+	Size        int
 	NumInpoint  uint32
 	Inpoints    []TxInput
 	NumOutpoint uint32
 	Outpoints   []TxOutput
 }
 
+// FeeRate returns the transaction fee rate in DCR/kB. Zero is returned if the
+// transaction size is unknown.
+func (tx *Transaction) FeeRate() float64 {
+	if tx.Size <= 0 {
+		return 0
+	}
+	return math.Round(tx.Fees*1000/float64(tx.Size)*1e8) / 1e8
+}
+
 // TxInput holds an inpoint transaction of a given transaction.
 type TxInput struct {
 	ValueIn       float64
@@ -45,9 +62,25 @@ type TxOutput struct {
 	PkScriptData ScriptPubKeyData
 }
 
-// ScriptPubKeyData holds the public key script decoded data.
+// ScriptTypeCommitment is the script type of the ticket commitment outputs.
+const ScriptTypeCommitment = "sstxcommitment"
+
+// ScriptPubKeyData holds the public key script decoded data. Addresses is
+// empty for nulldata and non-standard scripts. StakeSubclass is the class of
+// the script tagged by the stake opcode of a stake output, e.g. pubkeyhash for
+// a stakegen output paying to a public key hash. The ticket commitments hold
+// the reward address in Addresses and the committed amount in CommitAmount.
 type ScriptPubKeyData struct {
-	Addresses []string
-	Type      string
-	ReqSigs   int32
+	Addresses     []string
+	Type          string
+	ReqSigs       int32
+	StakeSubclass string
+	CommitAmount  float64
+}
+
+// IsNullData returns true if the script is a provably unspendable nulldata
+// script, ticket commitments included. Such outputs hold no funds that can be
+// traced.
+func (s ScriptPubKeyData) IsNullData() bool {
+	return s.Type == txscript.NullDataTy.String() || s.Type == ScriptTypeCommitment
 }
@@ -0,0 +1,153 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package rpcutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+)
+
+// defaultDcrdataTimeout is the maximum time a dcrdata API request may take.
+const defaultDcrdataTimeout = 30 * time.Second
+
+// dcrdataTx defines the part of the dcrdata /api/tx/{txid} response payload
+// needed to reconstruct the verbose transaction data.
+type dcrdataTx struct {
+	TxID     string        `json:"txid"`
+	Version  int32         `json:"version"`
+	Locktime uint32        `json:"locktime"`
+	Expiry   uint32        `json:"expiry"`
+	Vin      []dcrjson.Vin `json:"vin"`
+	Vout     []struct {
+		Value        float64                    `json:"value"`
+		N            uint32                     `json:"n"`
+		Version      uint16                     `json:"version"`
+		ScriptPubKey dcrjson.ScriptPubKeyResult `json:"scriptPubKey"`
+	} `json:"vout"`
+	Confirmations int64 `json:"confirmations"`
+	Block         *struct {
+		BlockHash   string `json:"blockhash"`
+		BlockHeight int64  `json:"blockheight"`
+		BlockIndex  uint32 `json:"blockindex"`
+		Time        int64  `json:"time"`
+		BlockTime   int64  `json:"blocktime"`
+	} `json:"block,omitempty"`
+}
+
+// DcrdataClient fetches the transactions data from a dcrdata instance REST API.
+// It implements the TxSource interface.
+type DcrdataClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewDcrdataClient returns a dcrdata REST API client for the dcrdata instance
+// at the provided url e.g. https://explorer.dcrdata.org.
+func NewDcrdataClient(baseURL string) *DcrdataClient {
+	return &DcrdataClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: defaultDcrdataTimeout},
+	}
+}
+
+// GetRawTransactionVerbose fetches the transaction and its serialized hex from
+// the dcrdata instance and maps them into the verbose transaction data that
+// dcrd would have returned.
+func (c *DcrdataClient) GetRawTransactionVerbose(txHash *chainhash.Hash) (
+	*dcrjson.TxRawResult, error) {
+	var tx dcrdataTx
+	if err := c.get("/api/tx/"+txHash.String(), &tx); err != nil {
+		return nil, err
+	}
+
+	var txHex string
+	if err := c.get("/api/tx/hex/"+txHash.String(), &txHex); err != nil {
+		return nil, err
+	}
+
+	return tx.toTxRawResult(txHex), nil
+}
+
+// get fetches the provided API path and decodes the response into v. Responses
+// decoded into a string are not expected to be JSON encoded.
+func (c *DcrdataClient) get(path string, v interface{}) error {
+	resp, err := c.client.Get(c.baseURL + path)
+	if err != nil {
+		return NewError(ErrNodeUnavailable,
+			fmt.Sprintf("dcrdata request %s failed: %v", path, err))
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading dcrdata response %s failed: %v", path, err)
+	}
+
+	switch {
+	// dcrdata responds with 422 to requests for unknown transactions.
+	case resp.StatusCode == http.StatusNotFound,
+		resp.StatusCode == http.StatusUnprocessableEntity:
+		return NewError(ErrTxNotFound,
+			fmt.Sprintf("dcrdata request %s failed: %s", path, resp.Status))
+
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return NewError(ErrNodeUnavailable,
+			fmt.Sprintf("dcrdata request %s failed: %s", path, resp.Status))
+
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("dcrdata request %s failed: %s", path, resp.Status)
+	}
+
+	if s, ok := v.(*string); ok {
+		*s = strings.Trim(strings.TrimSpace(string(body)), `"`)
+		return nil
+	}
+
+	if err = json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decoding dcrdata response %s failed: %v", path, err)
+	}
+	return nil
+}
+
+// toTxRawResult maps the dcrdata transaction into the dcrd verbose transaction
+// data format.
+func (tx *dcrdataTx) toTxRawResult(txHex string) *dcrjson.TxRawResult {
+	rawTx := &dcrjson.TxRawResult{
+		Hex:           txHex,
+		Txid:          tx.TxID,
+		Version:       tx.Version,
+		LockTime:      tx.Locktime,
+		Expiry:        tx.Expiry,
+		Vin:           tx.Vin,
+		Confirmations: tx.Confirmations,
+		Vout:          make([]dcrjson.Vout, len(tx.Vout)),
+	}
+
+	for i, out := range tx.Vout {
+		rawTx.Vout[i] = dcrjson.Vout{
+			Value:        out.Value,
+			N:            out.N,
+			Version:      out.Version,
+			ScriptPubKey: out.ScriptPubKey,
+		}
+	}
+
+	if tx.Block != nil {
+		rawTx.BlockHash = tx.Block.BlockHash
+		rawTx.BlockHeight = tx.Block.BlockHeight
+		rawTx.BlockIndex = tx.Block.BlockIndex
+		rawTx.Time = tx.Block.Time
+		rawTx.Blocktime = tx.Block.BlockTime
+	}
+
+	return rawTx
+}
@@ -0,0 +1,107 @@
+package rpcutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+)
+
+const dcrdataTestTxID = "9daffa4ed29257eb8cc170bf0bbb3a114d2e38961ca968ee5da9c8490d352c38"
+
+// newDcrdataServer returns a dcrdata stand-in that serves the recorded
+// fixtures in testdata/dcrdata.
+func newDcrdataServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var file string
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/tx/hex/"):
+			file = strings.TrimPrefix(r.URL.Path, "/api/tx/hex/") + ".hex"
+		case strings.HasPrefix(r.URL.Path, "/api/tx/"):
+			file = strings.TrimPrefix(r.URL.Path, "/api/tx/") + ".json"
+		}
+
+		if _, err := os.Stat(filepath.Join("testdata", "dcrdata", file)); file == "" || err != nil {
+			http.Error(w, "Unprocessable Entity", http.StatusUnprocessableEntity)
+			return
+		}
+
+		http.ServeFile(w, r, filepath.Join("testdata", "dcrdata", file))
+	}))
+}
+
+// TestDcrdataClient tests that the dcrdata transaction data is mapped to the
+// same transaction data dcrd would have provided.
+func TestDcrdataClient(t *testing.T) {
+	server := newDcrdataServer()
+	defer server.Close()
+
+	client := NewDcrdataClient(server.URL + "/")
+
+	txRaw, err := GetTransactionVerboseByID(client, dcrdataTestTxID)
+	if err != nil {
+		t.Fatalf("expected no error to be returned but found %v", err)
+	}
+
+	if !strings.HasPrefix(txRaw.Hex, "0100000003") {
+		t.Fatalf("expected the tx hex to be fetched but found %q", txRaw.Hex)
+	}
+
+	if txRaw.Blocktime != 1541417702 || txRaw.BlockHeight != 302116 {
+		t.Fatalf("expected the block data to be mapped but found (%d, %d)",
+			txRaw.Blocktime, txRaw.BlockHeight)
+	}
+
+	tx := ExtractRawTxTransaction(txRaw)
+
+	expected := &Transaction{
+		BlockTime:   1541417702,
+		TxID:        dcrdataTestTxID,
+		Fees:        0.000672,
+		NumInpoint:  3,
+		NumOutpoint: 4,
+	}
+
+	if tx.TxID != expected.TxID || tx.BlockTime != expected.BlockTime ||
+		tx.NumInpoint != expected.NumInpoint || tx.NumOutpoint != expected.NumOutpoint ||
+		tx.Fees != expected.Fees {
+		t.Fatalf("expected transaction (%+v) but found (%+v)", expected, tx)
+	}
+
+	expectedOut := TxOutput{
+		Value:   5035.67279067,
+		TxIndex: 3,
+		PkScriptData: ScriptPubKeyData{
+			Addresses: []string{"TsbR3wtvajABNdRojaFp1fBnxpTZfyUYLsJ"},
+			Type:      "pubkeyhash",
+			ReqSigs:   1,
+		},
+	}
+
+	if !reflect.DeepEqual(tx.Outpoints[3], expectedOut) {
+		t.Fatalf("expected output (%+v) but found (%+v)", expectedOut, tx.Outpoints[3])
+	}
+
+	if tx.Inpoints[2].TxHash != "2eeedfc0262daaa3e3d1e9b464b5907234653a3fd00c412e7ef2fdbf2cf7d501" ||
+		tx.Inpoints[2].OutputTxIndex != 2 || tx.Inpoints[2].ValueIn != 5076.66042217 {
+		t.Fatalf("expected the prevout data to be mapped but found (%+v)", tx.Inpoints[2])
+	}
+}
+
+// TestDcrdataClientUnknownTx tests that the tx not found error is returned for
+// transactions the dcrdata instance does not know about.
+func TestDcrdataClientUnknownTx(t *testing.T) {
+	server := newDcrdataServer()
+	defer server.Close()
+
+	hash, _ := chainhash.NewHashFromStr(testTxID)
+	_, err := NewDcrdataClient(server.URL).GetRawTransactionVerbose(hash)
+	if code, ok := ErrorCodeOf(err); !ok || code != ErrTxNotFound {
+		t.Fatalf("expected the %v error but found %v", ErrTxNotFound, err)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package rpcutils
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/dcrjson"
+)
+
+// ErrorCode identifies a kind of error returned while fetching or analyzing
+// transactions.
+type ErrorCode int
+
+const (
+	// ErrInvalidHash indicates that the transaction hash provided is not a
+	// valid hash.
+	ErrInvalidHash ErrorCode = iota
+
+	// ErrInvalidRequest indicates that the request data, such as a raw
+	// transaction hex or its previous outpoint amounts, is invalid.
+	ErrInvalidRequest
+
+	// ErrInvalidOutputIndex indicates that the transaction has no output at
+	// the index provided.
+	ErrInvalidOutputIndex
+
+	// ErrTxNotFound indicates that the transactions source does not have the
+	// transaction requested.
+	ErrTxNotFound
+
+	// ErrNodeUnavailable indicates that the transactions source could not be
+	// reached.
+	ErrNodeUnavailable
+
+	// ErrTxTooComplex indicates that the transaction has too many inputs and
+	// outputs combinations to be analyzed.
+	ErrTxTooComplex
+
+	// ErrAnalysisTimeout indicates that the analysis did not complete within
+	// the time allowed.
+	ErrAnalysisTimeout
+
+	// ErrLabelNotFound indicates that no entity label is set for the address
+	// requested.
+	ErrLabelNotFound
+
+	// ErrPathNotFound indicates that no funds flow path links the output to
+	// the source requested.
+	ErrPathNotFound
+)
+
+// errorCodeStrings maps the error codes to their machine readable names.
+var errorCodeStrings = map[ErrorCode]string{
+	ErrInvalidHash:        "invalid_hash",
+	ErrInvalidRequest:     "invalid_request",
+	ErrInvalidOutputIndex: "invalid_output_index",
+	ErrTxNotFound:         "tx_not_found",
+	ErrNodeUnavailable:    "node_unavailable",
+	ErrTxTooComplex:       "tx_too_complex",
+	ErrAnalysisTimeout:    "analysis_timeout",
+	ErrLabelNotFound:      "label_not_found",
+	ErrPathNotFound:       "path_not_found",
+}
+
+// String returns the ErrorCode as a machine readable name.
+func (e ErrorCode) String() string {
+	if s := errorCodeStrings[e]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("unknown_error_code_%d", int(e))
+}
+
+// Error identifies a typed error. The caller can use type assertions or
+// ErrorCodeOf to access the ErrorCode field and ascertain the specific reason
+// for the failure.
+type Error struct {
+	ErrorCode   ErrorCode
+	Description string
+}
+
+// Error satisfies the error interface and prints human-readable errors.
+func (e Error) Error() string {
+	return e.Description
+}
+
+// NewError creates an Error given a set of arguments.
+func NewError(c ErrorCode, desc string) Error {
+	return Error{ErrorCode: c, Description: desc}
+}
+
+// ErrorCodeOf returns the ErrorCode of err and true if err is an Error.
+func ErrorCodeOf(err error) (ErrorCode, bool) {
+	e, ok := err.(Error)
+	return e.ErrorCode, ok
+}
+
+// txSourceError converts the error returned by a transactions source while
+// fetching txHash into an Error. Errors that are already typed are returned
+// unchanged.
+func txSourceError(txHash string, err error) error {
+	switch e := err.(type) {
+	case Error:
+		return e
+
+	case *dcrjson.RPCError:
+		if e.Code == dcrjson.ErrRPCNoTxInfo {
+			return NewError(ErrTxNotFound, "transaction "+txHash+" was not found")
+		}
+		return err
+	}
+
+	return NewError(ErrNodeUnavailable,
+		fmt.Sprintf("fetching transaction %s failed: %v", txHash, err))
+}
@@ -4,6 +4,7 @@
 package rpcutils
 
 import (
+	"encoding/hex"
 	"math"
 
 	"github.com/decred/dcrd/blockchain/stake"
@@ -43,6 +44,7 @@ func ExtractBlockTransactions(blockData *wire.MsgBlock,
 			TxType:    int64(stake.DetermineTxType(sTx)),
 			TxTree:    wire.TxTreeStake,
 			BlockTime: block.Time,
+			Size:      sTx.SerializeSize(),
 		}
 
 		var sent, spent float64
@@ -62,29 +64,8 @@ func ExtractBlockTransactions(blockData *wire.MsgBlock,
 		tx.Inpoints = vins
 		tx.NumInpoint = uint32(len(vins))
 
-		vouts := make([]TxOutput, len(sTx.TxOut))
-
-		// Extract the transaction outputs.
-		for v, out := range sTx.TxOut {
-			chainParams := activeNet.ChainParams()
-			scriptClass, scriptAddrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(
-				out.Version, out.PkScript, chainParams)
-
-			addys := make([]string, 0, len(scriptAddrs))
-			for ia := range scriptAddrs {
-				addys = append(addys, scriptAddrs[ia].String())
-			}
-
-			vouts[v] = TxOutput{
-				Value:   dcrutil.Amount(out.Value).ToCoin(),
-				TxIndex: uint32(v),
-				PkScriptData: ScriptPubKeyData{
-					Addresses: addys,
-					ReqSigs:   int32(reqSigs),
-					Type:      scriptClass.String(),
-				},
-			}
-
+		vouts := extractMsgTxOutputs(sTx.TxOut, stake.TxType(tx.TxType), activeNet)
+		for v := range vouts {
 			sent += vouts[v].Value
 		}
 
@@ -98,10 +79,111 @@ func ExtractBlockTransactions(blockData *wire.MsgBlock,
 	return txs
 }
 
+// ExtractMsgTxTransaction extracts the transaction with all its inputs and
+// outputs from a decoded transaction that may not be on chain yet. amountsIn
+// holds the previous outpoints amounts of the transaction inputs in their
+// respective order.
+func ExtractMsgTxTransaction(msgTx *wire.MsgTx, amountsIn []float64,
+	activeNet networkconfig.NetworkType) *Transaction {
+	txType := stake.DetermineTxType(msgTx)
+	tx := &Transaction{
+		TxID:   msgTx.TxHash().String(),
+		TxType: int64(txType),
+		TxTree: wire.TxTreeRegular,
+		Size:   msgTx.SerializeSize(),
+	}
+
+	if txType != stake.TxTypeRegular {
+		tx.TxTree = wire.TxTreeStake
+	}
+
+	var sent, spent float64
+	vins := make([]TxInput, len(msgTx.TxIn))
+
+	// Extract inputs
+	for v, in := range msgTx.TxIn {
+		vins[v] = TxInput{
+			TxHash:        in.PreviousOutPoint.Hash.String(),
+			ValueIn:       amountsIn[v],
+			OutputTxIndex: in.PreviousOutPoint.Index,
+		}
+		sent += amountsIn[v]
+	}
+
+	tx.Inpoints = vins
+	tx.NumInpoint = uint32(len(vins))
+
+	vouts := extractMsgTxOutputs(msgTx.TxOut, txType, activeNet)
+	for v := range vouts {
+		spent += vouts[v].Value
+	}
+
+	tx.Outpoints = vouts
+	tx.Sent = sent
+	tx.NumOutpoint = uint32(len(vouts))
+	tx.Fees = math.Round((sent-spent)*10e8) / 10e8
+	return tx
+}
+
+// extractMsgTxOutputs extracts the outputs data from the provided transaction
+// outputs. The commitments of the ticket outputs are decoded.
+func extractMsgTxOutputs(txOuts []*wire.TxOut, txType stake.TxType,
+	activeNet networkconfig.NetworkType) []TxOutput {
+	chainParams := activeNet.ChainParams()
+	vouts := make([]TxOutput, len(txOuts))
+
+	for v, out := range txOuts {
+		scriptClass, scriptAddrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(
+			out.Version, out.PkScript, chainParams)
+
+		addys := make([]string, 0, len(scriptAddrs))
+		for ia := range scriptAddrs {
+			addys = append(addys, scriptAddrs[ia].String())
+		}
+
+		script := ScriptPubKeyData{
+			Addresses:     addys,
+			ReqSigs:       int32(reqSigs),
+			Type:          scriptClass.String(),
+			StakeSubclass: stakeSubclass(out.PkScript),
+		}
+
+		// The ticket commitments are nulldata scripts at the odd output
+		// indexes that encode the reward address and amount.
+		if txType == stake.TxTypeSStx && v%2 == 1 && scriptClass == txscript.NullDataTy {
+			addr, err := stake.AddrFromSStxPkScrCommitment(out.PkScript, chainParams)
+			if err == nil {
+				amount, _ := stake.AmountFromSStxPkScrCommitment(out.PkScript)
+				script = ScriptPubKeyData{
+					Addresses:    []string{addr.String()},
+					Type:         ScriptTypeCommitment,
+					CommitAmount: amount.ToCoin(),
+				}
+			}
+		}
+
+		vouts[v] = TxOutput{
+			Value:        dcrutil.Amount(out.Value).ToCoin(),
+			TxIndex:      uint32(v),
+			PkScriptData: script,
+		}
+	}
+	return vouts
+}
+
 // ExtractRawTxTransaction extracts the transaction with all its inputs and
 // outputs from a single transaction raw tx data.
 func ExtractRawTxTransaction(rawTx *dcrjson.TxRawResult) *Transaction {
-	tx := &Transaction{TxID: rawTx.Txid}
+	// The raw tx hex holds the serialized transaction.
+	tx := &Transaction{TxID: rawTx.Txid, Size: len(rawTx.Hex) / 2}
+
+	// The tx type is read from the serialized transaction if it is set.
+	if msgTx, err := DecodeRawTx(rawTx.Hex); err == nil {
+		tx.TxType = int64(stake.DetermineTxType(msgTx))
+		if stake.TxType(tx.TxType) != stake.TxTypeRegular {
+			tx.TxTree = wire.TxTreeStake
+		}
+	}
 
 	var sent, spent float64
 	vins := make([]TxInput, len(rawTx.Vin))
@@ -125,16 +207,25 @@ func ExtractRawTxTransaction(rawTx *dcrjson.TxRawResult) *Transaction {
 
 	// Extract outputs
 	for v, out := range rawTx.Vout {
+		// An invalid script hex decodes to an empty script which has no stake
+		// subclass.
+		pkScript, _ := hex.DecodeString(out.ScriptPubKey.Hex)
+
 		vouts[v] = TxOutput{
 			Value:   out.Value,
 			TxIndex: out.N,
 			PkScriptData: ScriptPubKeyData{
-				Addresses: out.ScriptPubKey.Addresses,
-				ReqSigs:   out.ScriptPubKey.ReqSigs,
-				Type:      out.ScriptPubKey.Type,
+				Addresses:     out.ScriptPubKey.Addresses,
+				ReqSigs:       out.ScriptPubKey.ReqSigs,
+				Type:          out.ScriptPubKey.Type,
+				StakeSubclass: stakeSubclass(pkScript),
 			},
 		}
 
+		if out.ScriptPubKey.CommitAmt != nil {
+			vouts[v].PkScriptData.CommitAmount = *out.ScriptPubKey.CommitAmt
+		}
+
 		spent += out.Value
 	}
 
@@ -144,3 +235,17 @@ func ExtractRawTxTransaction(rawTx *dcrjson.TxRawResult) *Transaction {
 	tx.Fees = math.Round((sent-spent)*10e8) / 10e8
 	return tx
 }
+
+// stakeSubclass returns the class of the script tagged by the stake opcode of
+// a stake output script. An empty string is returned for the other scripts.
+func stakeSubclass(pkScript []byte) string {
+	if !txscript.IsStakeOutput(pkScript) {
+		return ""
+	}
+
+	class, err := txscript.GetStakeOutSubclass(pkScript)
+	if err != nil {
+		return ""
+	}
+	return class.String()
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package rpcutils
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// rpcDuration tracks the latency of the dcrd rpc calls per method.
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "dca",
+		Subsystem: "dcrd",
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of the dcrd rpc calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// rpcErrors counts the failed dcrd rpc calls per method.
+	rpcErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "dca",
+		Subsystem: "dcrd",
+		Name:      "rpc_errors_total",
+		Help:      "Number of failed dcrd rpc calls.",
+	}, []string{"method"})
+)
+
+func init() {
+	prometheus.MustRegister(rpcDuration, rpcErrors)
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package rpcutils
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/decred/dcrd/rpcclient"
+	"github.com/decred/dcrd/wire"
+)
+
+// defaultRetryInterval is the period a dcrd backend that failed a request is
+// kept out of the requests routing before it is tried again.
+const defaultRetryInterval = 30 * time.Second
+
+// NodeConfig defines the connection details of a single dcrd backend.
+type NodeConfig struct {
+	Host       string
+	User       string
+	Pass       string
+	Cert       string
+	DisableTLS bool
+}
+
+// node holds a dcrd backend and its health status. client is nil until the
+// backend could be connected to.
+type node struct {
+	host    string
+	cfg     NodeConfig
+	client  *rpcclient.Client
+	version *RPCVersion
+
+	// downUntil is the time before which the node is considered unhealthy.
+	downUntil time.Time
+}
+
+// NodePool routes requests to a set of dcrd backends. Requests are spread
+// across the healthy backends in a round robin manner and a request that
+// fails because of a backend connection problem is retried on the next
+// healthy backend.
+type NodePool struct {
+	mtx           sync.RWMutex
+	nodes         []*node
+	next          uint32
+	retryInterval time.Duration
+}
+
+// NewNodePool connects to all the provided dcrd backends. Backends that cannot
+// be connected to are logged and kept in the pool marked as unhealthy, they
+// are connected to once their retry interval is over. An error is only
+// returned if none of the backends could be connected to.
+func NewNodePool(cfgs []NodeConfig) (*NodePool, error) {
+	pool := &NodePool{retryInterval: defaultRetryInterval}
+
+	var connected int
+	for _, cfg := range cfgs {
+		n := &node{host: cfg.Host, cfg: cfg}
+		pool.nodes = append(pool.nodes, n)
+
+		if err := pool.connect(n); err != nil {
+			log.Warnf("Keeping dcrd backend %s marked as down: %v", cfg.Host, err)
+			pool.markDown(n)
+			continue
+		}
+
+		connected++
+		log.Infof("Connected to dcrd backend %s: %s", cfg.Host, n.version.String())
+	}
+
+	if connected == 0 {
+		return nil, errors.New("failed to connect to any of the dcrd backends")
+	}
+
+	return pool, nil
+}
+
+// connect connects to the backend if it is not connected yet.
+func (p *NodePool) connect(n *node) error {
+	p.mtx.RLock()
+	isConnected := n.client != nil
+	p.mtx.RUnlock()
+
+	if isConnected {
+		return nil
+	}
+
+	client, version, err := ConnectRPCNode(n.cfg.Host, n.cfg.User, n.cfg.Pass,
+		n.cfg.Cert, n.cfg.DisableTLS)
+	if err != nil {
+		return err
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	// A concurrent request may have connected to the backend already.
+	if n.client != nil {
+		client.Shutdown()
+		return nil
+	}
+
+	n.client, n.version = client, version
+	return nil
+}
+
+// Version returns the RPC version of the first connected dcrd backend.
+func (p *NodePool) Version() *RPCVersion {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	for _, n := range p.nodes {
+		if n.version != nil {
+			return n.version
+		}
+	}
+	return nil
+}
+
+// Size returns the number of dcrd backends managed by the pool.
+func (p *NodePool) Size() int {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	return len(p.nodes)
+}
+
+// Connected returns the number of dcrd backends connected to.
+func (p *NodePool) Connected() int {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	var count int
+	for _, n := range p.nodes {
+		if n.client != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Shutdown disconnects all the connected dcrd backends in the pool.
+func (p *NodePool) Shutdown() {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	for _, n := range p.nodes {
+		if n.client != nil {
+			n.client.Shutdown()
+		}
+	}
+}
+
+// GetRawTransactionVerbose fetches the verbose transaction data from one of the
+// healthy dcrd backends. It implements the TxSource interface.
+func (p *NodePool) GetRawTransactionVerbose(txHash *chainhash.Hash) (
+	*dcrjson.TxRawResult, error) {
+	var txRaw *dcrjson.TxRawResult
+	err := p.do("getrawtransaction", func(c *rpcclient.Client) (err error) {
+		txRaw, err = c.GetRawTransactionVerbose(txHash)
+		return
+	})
+	return txRaw, err
+}
+
+// GetBlockHash fetches the block hash at the given height from one of the
+// healthy dcrd backends.
+func (p *NodePool) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	var hash *chainhash.Hash
+	err := p.do("getblockhash", func(c *rpcclient.Client) (err error) {
+		hash, err = c.GetBlockHash(height)
+		return
+	})
+	return hash, err
+}
+
+// GetBlock fetches the block with the given hash from one of the healthy dcrd
+// backends.
+func (p *NodePool) GetBlock(blockHash *chainhash.Hash) (*wire.MsgBlock, error) {
+	var block *wire.MsgBlock
+	err := p.do("getblock", func(c *rpcclient.Client) (err error) {
+		block, err = c.GetBlock(blockHash)
+		return
+	})
+	return block, err
+}
+
+// do runs the request fn on the healthy dcrd backends until one of them
+// returns a response. The backends not connected to yet are connected to
+// first. Errors returned by the dcrd node itself are returned immediately
+// since retrying them on a different backend would not help.
+func (p *NodePool) do(method string, fn func(c *rpcclient.Client) error) error {
+	var lastErr error
+
+	for _, n := range p.candidates(time.Now()) {
+		if err := p.connect(n); err != nil {
+			log.Warnf("Connecting to dcrd backend %s failed: %v", n.host, err)
+
+			p.markDown(n)
+			lastErr = err
+			continue
+		}
+
+		p.mtx.RLock()
+		client := n.client
+		p.mtx.RUnlock()
+
+		t := time.Now()
+		err := fn(client)
+		rpcDuration.WithLabelValues(method).Observe(time.Since(t).Seconds())
+		if err != nil {
+			rpcErrors.WithLabelValues(method).Inc()
+		}
+
+		if err == nil || !isConnectionError(err) {
+			return err
+		}
+
+		log.Warnf("%s request on dcrd backend %s failed: %v", method, n.host, err)
+
+		p.markDown(n)
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no healthy dcrd backend available")
+	}
+
+	return NewError(ErrNodeUnavailable,
+		fmt.Sprintf("%s failed on all dcrd backends: %v", method, lastErr))
+}
+
+// candidates returns the backends in the order in which they should be tried.
+// The healthy backends are rotated so that the load is spread across them and
+// the unhealthy backends are appended at the end as the last resort.
+func (p *NodePool) candidates(now time.Time) []*node {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	start := int(atomic.AddUint32(&p.next, 1)-1) % len(p.nodes)
+
+	healthy := make([]*node, 0, len(p.nodes))
+	var unhealthy []*node
+
+	for i := range p.nodes {
+		n := p.nodes[(start+i)%len(p.nodes)]
+		if now.Before(n.downUntil) || (n.client != nil && n.client.Disconnected()) {
+			unhealthy = append(unhealthy, n)
+			continue
+		}
+		healthy = append(healthy, n)
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+// markDown flags the provided backend as unhealthy for the retry interval.
+func (p *NodePool) markDown(n *node) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	n.downUntil = time.Now().Add(p.retryInterval)
+}
+
+// isConnectionError returns true if the error was not returned by the dcrd
+// node, i.e. the request never got a response.
+func isConnectionError(err error) bool {
+	_, ok := err.(*dcrjson.RPCError)
+	return !ok
+}
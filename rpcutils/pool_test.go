@@ -0,0 +1,171 @@
+package rpcutils
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/decred/dcrd/rpcclient"
+)
+
+const testTxID = "ae40333aed99c0b004ef01834444944c471bc72ce869949f6f8ced728941f561"
+
+// newTestNode returns a pool node whose client sends its requests over HTTP
+// POST to the provided host.
+func newTestNode(t *testing.T, host string) *node {
+	client, err := rpcclient.New(&rpcclient.ConnConfig{
+		Host:         host,
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("expected no error creating the rpc client but found %v", err)
+	}
+	return &node{host: host, client: client}
+}
+
+// newTestServer returns a dcrd stand-in that replies to every request with
+// the provided result or rpc error.
+func newTestServer(result interface{}, rpcErr *dcrjson.RPCError) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req dcrjson.Request
+		json.NewDecoder(r.Body).Decode(&req)
+
+		marshalled, _ := dcrjson.MarshalResponse("1.0", req.ID, result, rpcErr)
+		w.Write(marshalled)
+	}))
+}
+
+// unusedHost returns the address of a port nothing is listening on.
+func unusedHost(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected no error listening but found %v", err)
+	}
+	host := l.Addr().String()
+	l.Close()
+	return host
+}
+
+// TestNodePoolFailover tests that a request is retried on the next backend
+// when the current backend cannot be reached.
+func TestNodePoolFailover(t *testing.T) {
+	server := newTestServer(&dcrjson.TxRawResult{Txid: testTxID}, nil)
+	defer server.Close()
+
+	down := newTestNode(t, unusedHost(t))
+	up := newTestNode(t, strings.TrimPrefix(server.URL, "http://"))
+
+	pool := &NodePool{nodes: []*node{down, up}, retryInterval: time.Minute}
+
+	hash, _ := chainhash.NewHashFromStr(testTxID)
+	tx, err := pool.GetRawTransactionVerbose(hash)
+	if err != nil {
+		t.Fatalf("expected no error to be returned but found %v", err)
+	}
+
+	if tx.Txid != testTxID {
+		t.Fatalf("expected txid %s but found %s", testTxID, tx.Txid)
+	}
+
+	if !time.Now().Before(down.downUntil) {
+		t.Fatal("expected the unreachable backend to be marked as unhealthy")
+	}
+
+	if up.downUntil != (time.Time{}) {
+		t.Fatal("expected the reachable backend to remain healthy")
+	}
+}
+
+// TestNodePoolRPCError tests that errors returned by the dcrd node are not
+// retried on the other backends.
+func TestNodePoolRPCError(t *testing.T) {
+	var hits int
+	server := newTestServer(nil, dcrjson.NewRPCError(dcrjson.ErrRPCNoTxInfo,
+		"No information available about transaction"))
+	defer server.Close()
+
+	counter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer counter.Close()
+
+	first := newTestNode(t, strings.TrimPrefix(counter.URL, "http://"))
+	second := newTestNode(t, strings.TrimPrefix(counter.URL, "http://"))
+
+	pool := &NodePool{nodes: []*node{first, second}, retryInterval: time.Minute}
+
+	hash, _ := chainhash.NewHashFromStr(testTxID)
+	_, err := pool.GetRawTransactionVerbose(hash)
+	if _, ok := err.(*dcrjson.RPCError); !ok {
+		t.Fatalf("expected a *dcrjson.RPCError to be returned but found %T", err)
+	}
+
+	if hits != 1 {
+		t.Fatalf("expected the request to be sent once but it was sent %d times", hits)
+	}
+}
+
+// TestCandidates tests that the healthy backends are rotated and the unhealthy
+// ones are tried last.
+func TestCandidates(t *testing.T) {
+	a := newTestNode(t, "a:9109")
+	b := newTestNode(t, "b:9109")
+	c := newTestNode(t, "c:9109")
+
+	now := time.Now()
+	b.downUntil = now.Add(time.Minute)
+
+	pool := &NodePool{nodes: []*node{a, b, c}}
+
+	td := [][]*node{{a, c, b}, {c, a, b}, {c, a, b}, {a, c, b}}
+
+	for i, expected := range td {
+		result := pool.candidates(now)
+		for k := range expected {
+			if result[k] != expected[k] {
+				t.Fatalf("round %d: expected node %s at position %d but found %s",
+					i, expected[k].host, k, result[k].host)
+			}
+		}
+	}
+}
+
+// TestNodePoolReconnect tests that a backend not connected to is connected to
+// on a request and kept marked as down if it still cannot be reached.
+func TestNodePoolReconnect(t *testing.T) {
+	server := newTestServer(&dcrjson.TxRawResult{Txid: testTxID}, nil)
+	defer server.Close()
+
+	host := unusedHost(t)
+	down := &node{host: host, cfg: NodeConfig{Host: host, DisableTLS: true}}
+	up := newTestNode(t, strings.TrimPrefix(server.URL, "http://"))
+
+	pool := &NodePool{nodes: []*node{down, up}, retryInterval: time.Minute}
+
+	if pool.Connected() != 1 || pool.Size() != 2 {
+		t.Fatalf("expected 1 of 2 backends connected but found %d of %d",
+			pool.Connected(), pool.Size())
+	}
+
+	hash, _ := chainhash.NewHashFromStr(testTxID)
+	tx, err := pool.GetRawTransactionVerbose(hash)
+	if err != nil {
+		t.Fatalf("expected no error to be returned but found %v", err)
+	}
+
+	if tx.Txid != testTxID {
+		t.Fatalf("expected txid %s but found %s", testTxID, tx.Txid)
+	}
+
+	if down.client != nil || !time.Now().Before(down.downUntil) {
+		t.Fatal("expected the unreachable backend to be kept marked as down")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package rpcutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+)
+
+// Recorder wraps a TxSource and saves every transaction fetched through it as
+// a fixture file in the recording directory. The fixtures can be served back
+// by a Replayer.
+type Recorder struct {
+	mtx    sync.Mutex
+	source TxSource
+	dir    string
+}
+
+// NewRecorder returns a Recorder that saves the transactions fetched from the
+// source in dir. dir is created if it does not exist.
+func NewRecorder(source TxSource, dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the recording directory: %v", err)
+	}
+
+	return &Recorder{source: source, dir: dir}, nil
+}
+
+// GetRawTransactionVerbose fetches the transaction from the wrapped source and
+// records it. Failing to record a transaction is logged but not returned.
+func (r *Recorder) GetRawTransactionVerbose(txHash *chainhash.Hash) (
+	*dcrjson.TxRawResult, error) {
+	txRaw, err := r.source.GetRawTransactionVerbose(txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(txRaw, "", "  ")
+	if err != nil {
+		log.Warnf("Failed to encode transaction %s fixture: %v", txHash, err)
+		return txRaw, nil
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	err = ioutil.WriteFile(fixturePath(r.dir, txHash), append(data, '\n'), 0600)
+	if err != nil {
+		log.Warnf("Failed to record transaction %s: %v", txHash, err)
+	}
+
+	return txRaw, nil
+}
+
+// Replayer serves the transactions fixtures recorded by a Recorder. It
+// implements the TxSource interface.
+type Replayer struct {
+	dir string
+}
+
+// NewReplayer returns a Replayer that serves the transaction fixtures in dir.
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{dir: dir}
+}
+
+// GetRawTransactionVerbose returns the recorded transaction. Transactions that
+// were not recorded return the same error dcrd returns for unknown
+// transactions.
+func (r *Replayer) GetRawTransactionVerbose(txHash *chainhash.Hash) (
+	*dcrjson.TxRawResult, error) {
+	data, err := ioutil.ReadFile(fixturePath(r.dir, txHash))
+	if os.IsNotExist(err) {
+		return nil, dcrjson.NewRPCError(dcrjson.ErrRPCNoTxInfo,
+			"No information available about transaction "+txHash.String())
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transaction %s fixture: %v", txHash, err)
+	}
+
+	txRaw := new(dcrjson.TxRawResult)
+	if err = json.Unmarshal(data, txRaw); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction %s fixture: %v", txHash, err)
+	}
+
+	return txRaw, nil
+}
+
+// fixturePath returns the path of the transaction fixture file in dir.
+func fixturePath(dir string, txHash *chainhash.Hash) string {
+	return filepath.Join(dir, txHash.String()+".json")
+}
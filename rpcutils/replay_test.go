@@ -0,0 +1,60 @@
+package rpcutils
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+)
+
+// TestRecordAndReplay tests that the transactions recorded by a Recorder are
+// served back unchanged by a Replayer.
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dca-replay")
+	if err != nil {
+		t.Fatalf("expected no error creating the temp dir but found %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := newDcrdataServer()
+	defer server.Close()
+
+	recorder, err := NewRecorder(NewDcrdataClient(server.URL), dir)
+	if err != nil {
+		t.Fatalf("expected no error creating the recorder but found %v", err)
+	}
+
+	recorded, err := GetTransactionVerboseByID(recorder, dcrdataTestTxID)
+	if err != nil {
+		t.Fatalf("expected no error fetching the tx but found %v", err)
+	}
+
+	replayed, err := GetTransactionVerboseByID(NewReplayer(dir), dcrdataTestTxID)
+	if err != nil {
+		t.Fatalf("expected no error replaying the tx but found %v", err)
+	}
+
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Fatalf("expected the replayed tx (%+v) to match the recorded tx (%+v)",
+			replayed, recorded)
+	}
+
+	// Transactions that were not recorded return the dcrd unknown tx error.
+	hash, _ := chainhash.NewHashFromStr(testTxID)
+	_, err = NewReplayer(dir).GetRawTransactionVerbose(hash)
+	if e, ok := err.(*dcrjson.RPCError); !ok || e.Code != dcrjson.ErrRPCNoTxInfo {
+		t.Fatalf("expected the unknown tx rpc error but found %v", err)
+	}
+
+	// Failed requests are not recorded.
+	if _, err = recorder.GetRawTransactionVerbose(hash); err == nil {
+		t.Fatal("expected an error fetching an unknown tx but none was returned")
+	}
+
+	if _, err = os.Stat(fixturePath(dir, hash)); !os.IsNotExist(err) {
+		t.Fatal("expected the failed request not to be recorded")
+	}
+}
@@ -5,6 +5,8 @@
 package rpcutils
 
 import (
+	"bytes"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/decred/dcrd/dcrjson"
 	"github.com/decred/dcrd/dcrutil"
 	"github.com/decred/dcrd/rpcclient"
+	"github.com/decred/dcrd/wire"
 )
 
 // RPCVersion defines the semantic versioning configuration.
@@ -21,6 +24,20 @@ type RPCVersion struct {
 	Patch uint32 `json:"patch"`
 }
 
+// TxSource defines the methods a transactions data backend needs to implement
+// so as to feed the analytics with transactions. *rpcclient.Client satisfies
+// this interface.
+type TxSource interface {
+	GetRawTransactionVerbose(txHash *chainhash.Hash) (*dcrjson.TxRawResult, error)
+}
+
+// BlockSource defines the methods needed to fetch blocks from a chain server.
+// *rpcclient.Client and *NodePool satisfy this interface.
+type BlockSource interface {
+	GetBlockHash(blockHeight int64) (*chainhash.Hash, error)
+	GetBlock(blockHash *chainhash.Hash) (*wire.MsgBlock, error)
+}
+
 func (v *RPCVersion) String() string {
 	return fmt.Sprintf("RPC Version (V%d.%d.%d)", v.Major, v.Minor, v.Patch)
 }
@@ -87,7 +104,7 @@ func ConnectRPCNode(host, user, pass, cert string, disableTLS bool,
 }
 
 // GetBlock gets a block at the given height from a chain server.
-func GetBlock(client *rpcclient.Client, height int64) (*dcrutil.Block,
+func GetBlock(client BlockSource, height int64) (*dcrutil.Block,
 	*chainhash.Hash, error) {
 	blockhash, err := client.GetBlockHash(height)
 	if err != nil {
@@ -99,7 +116,7 @@ func GetBlock(client *rpcclient.Client, height int64) (*dcrutil.Block,
 }
 
 // GetBlockByHash gets the block with the given hash from a chain server.
-func GetBlockByHash(client *rpcclient.Client, blockhash *chainhash.Hash) (
+func GetBlockByHash(client BlockSource, blockhash *chainhash.Hash) (
 	*dcrutil.Block, error) {
 	msgBlock, err := client.GetBlock(blockhash)
 	if err != nil {
@@ -111,18 +128,19 @@ func GetBlockByHash(client *rpcclient.Client, blockhash *chainhash.Hash) (
 }
 
 // GetTransactionVerboseByID get a transaction by transaction id
-func GetTransactionVerboseByID(client *rpcclient.Client, txid string) (
+func GetTransactionVerboseByID(client TxSource, txid string) (
 	*dcrjson.TxRawResult, error) {
 	txhash, err := chainhash.NewHashFromStr(txid)
 	if err != nil {
 		log.Errorf("Invalid transaction hash %s", txid)
-		return nil, err
+		return nil, NewError(ErrInvalidHash, fmt.Sprintf("invalid transaction hash %s: %v",
+			txid, err))
 	}
 
 	txraw, err := client.GetRawTransactionVerbose(txhash)
 	if err != nil {
 		log.Errorf("GetRawTransactionVerbose failed for: %v", txhash)
-		return nil, err
+		return nil, txSourceError(txid, err)
 	}
 	return txraw, nil
 }
@@ -143,3 +161,18 @@ func SearchRawTransaction(client *rpcclient.Client, count int, address string) (
 	}
 	return txs, nil
 }
+
+// DecodeRawTx decodes the provided serialized transaction hex.
+func DecodeRawTx(txHex string) (*wire.MsgTx, error) {
+	serializedTx, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, NewError(ErrInvalidRequest, fmt.Sprintf("invalid transaction hex: %v", err))
+	}
+
+	msgTx := wire.NewMsgTx()
+	if err = msgTx.Deserialize(bytes.NewReader(serializedTx)); err != nil {
+		return nil, NewError(ErrInvalidRequest,
+			fmt.Sprintf("failed to deserialize transaction: %v", err))
+	}
+	return msgTx, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestGenCertPair tests that the generated cert and key make a valid pair and
+// that the cert is valid for the localhost and the extra hosts.
+func TestGenCertPair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dca-tls")
+	if err != nil {
+		t.Fatalf("expected no error creating the temp dir but found %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "certs", "rpc.cert")
+	keyFile := filepath.Join(dir, "certs", "rpc.key")
+
+	if err = genCertPair(certFile, keyFile, "dca.example.com"); err != nil {
+		t.Fatalf("expected no error generating the cert pair but found %v", err)
+	}
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("expected a valid cert pair but found %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("expected no error parsing the cert but found %v", err)
+	}
+
+	for _, host := range []string{"localhost", "127.0.0.1", "::1", "dca.example.com"} {
+		if err = cert.VerifyHostname(host); err != nil {
+			t.Fatalf("expected the cert to be valid for %s but found %v", host, err)
+		}
+	}
+}
+
+// TestIsLocalhost tests that only the loopback listening addresses are
+// reported as localhost.
+func TestIsLocalhost(t *testing.T) {
+	type testData struct {
+		Addr     string
+		Expected bool
+	}
+
+	td := []testData{
+		{Addr: "127.0.0.1:8476", Expected: true},
+		{Addr: "localhost:8476", Expected: true},
+		{Addr: "[::1]:8476", Expected: true},
+		{Addr: "127.0.0.1", Expected: true},
+		{Addr: "0.0.0.0:8476", Expected: false},
+		{Addr: ":8476", Expected: false},
+		{Addr: "10.0.0.2:8476", Expected: false},
+		{Addr: "dca.example.com:8476", Expected: false},
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			if result := isLocalhost(data.Addr); result != data.Expected {
+				t.Fatalf("expected %s localhost check to be %v but found %v", data.Addr,
+					data.Expected, result)
+			}
+		})
+	}
+}
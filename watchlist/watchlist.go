@@ -0,0 +1,488 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+// Package watchlist follows the funds of the watched addresses and outpoints
+// through the new blocks and raises an alert for each new output they fund.
+package watchlist
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+	"github.com/raedahgroup/dcrchainanalysis/v1/analytics"
+	"github.com/raedahgroup/dcrchainanalysis/v1/networkconfig"
+	"github.com/raedahgroup/dcrchainanalysis/v1/rpcutils"
+)
+
+// blocksQueueSize is the number of connected blocks waiting to be processed
+// before the new blocks are dropped.
+const blocksQueueSize = 32
+
+// Config defines the watched sources and how their alerts are raised and
+// delivered.
+type Config struct {
+	// Addresses and Outpoints are the watched sources. The outpoints are set
+	// as "txhash:vout".
+	Addresses []string
+	Outpoints []string
+
+	// MinProbability is the least link probability from a watched input to a
+	// new output that raises an alert.
+	MinProbability float64
+
+	// AlertsFile is the file the alerts are saved to.
+	AlertsFile string
+
+	// Webhooks are the URLs the alerts are posted to. A failed delivery is
+	// retried Retries times, waiting RetryInterval before the first retry and
+	// doubling the wait on each retry.
+	Webhooks      []string
+	Retries       int
+	RetryInterval time.Duration
+
+	ActiveNet networkconfig.NetworkType
+}
+
+// Alert is a new output funded by a watched source. Source is the watched
+// address or outpoint the funds come from and Spent the watched outpoint spent
+// by the tx, which may be an output alerted earlier. Probability is the link
+// probability from the spent outpoint to the output.
+type Alert struct {
+	ID          int
+	Time        int64
+	BlockHeight int64
+	BlockHash   string
+	TxHash      string
+	Vout        uint32
+	Amount      float64
+	Addresses   []string `json:",omitempty"`
+	Probability float64
+	Source      string
+	Spent       string
+}
+
+// Watcher checks the new blocks for the txs spending the watched sources. The
+// outputs alerted are watched in turn so the funds are followed as they keep
+// moving. The Watcher is safe for concurrent access.
+type Watcher struct {
+	// processMtx processes the blocks one at a time. mtx guards the alerts
+	// and the watched outpoints and is not held while the txs are checked.
+	processMtx sync.Mutex
+	mtx        sync.RWMutex
+
+	cfg       Config
+	client    rpcutils.TxSource
+	addresses map[string]bool
+	outpoints map[string]string
+	alerts    []*Alert
+	notifier  *notifier
+
+	blocks chan chainhash.Hash
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New returns a Watcher of the configured sources. The alerts saved earlier
+// are loaded and their outputs are watched again. client is used to find the
+// addresses spent by the txs inputs if addresses are watched.
+func New(cfg Config, client rpcutils.TxSource) (*Watcher, error) {
+	if cfg.MinProbability <= 0 || cfg.MinProbability > 1 {
+		return nil, fmt.Errorf("invalid minimum alert probability %v: expected a value "+
+			"greater than 0 and not greater than 1", cfg.MinProbability)
+	}
+
+	w := &Watcher{
+		cfg:       cfg,
+		client:    client,
+		addresses: make(map[string]bool),
+		outpoints: make(map[string]string),
+		notifier:  newNotifier(cfg.Webhooks, cfg.Retries, cfg.RetryInterval),
+		blocks:    make(chan chainhash.Hash, blocksQueueSize),
+		quit:      make(chan struct{}),
+	}
+
+	for _, addr := range cfg.Addresses {
+		w.addresses[addr] = true
+	}
+
+	for _, s := range cfg.Outpoints {
+		op, err := parseOutpoint(s)
+		if err != nil {
+			return nil, err
+		}
+		w.outpoints[op] = op
+	}
+
+	if err := w.loadAlerts(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// parseOutpoint parses the outpoint formatted as "txhash:vout" and returns it
+// in the same format with the lowercase tx hash.
+func parseOutpoint(op string) (string, error) {
+	i := strings.LastIndex(op, ":")
+	if i == chainhash.MaxHashStringSize {
+		hash, err := chainhash.NewHashFromStr(op[:i])
+		if err == nil {
+			vout, err := strconv.ParseUint(op[i+1:], 10, 32)
+			if err == nil {
+				return outpointKey(hash.String(), uint32(vout)), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid watched outpoint %q: expected txhash:vout", op)
+}
+
+// outpointKey returns the outpoint formatted as "txhash:vout".
+func outpointKey(txHash string, vout uint32) string {
+	return txHash + ":" + strconv.FormatUint(uint64(vout), 10)
+}
+
+// loadAlerts reads the alerts saved to the alerts file.
+func (w *Watcher) loadAlerts() error {
+	f, err := os.Open(w.cfg.AlertsFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to open the alerts file: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		alert := new(Alert)
+		if err = json.Unmarshal([]byte(line), alert); err != nil {
+			return fmt.Errorf("failed to decode the alerts file %s: %v", w.cfg.AlertsFile, err)
+		}
+
+		w.alerts = append(w.alerts, alert)
+		w.outpoints[outpointKey(alert.TxHash, alert.Vout)] = alert.Source
+	}
+
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read the alerts file %s: %v", w.cfg.AlertsFile, err)
+	}
+	return nil
+}
+
+// saveAlerts appends the alerts to the alerts file.
+func (w *Watcher) saveAlerts(alerts []*Alert) error {
+	if err := os.MkdirAll(filepath.Dir(w.cfg.AlertsFile), 0700); err != nil {
+		return fmt.Errorf("failed to create the alerts directory: %v", err)
+	}
+
+	f, err := os.OpenFile(w.cfg.AlertsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the alerts file: %v", err)
+	}
+
+	enc := json.NewEncoder(f)
+	for _, alert := range alerts {
+		if err = enc.Encode(alert); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to save the alert %d: %v", alert.ID, err)
+		}
+	}
+	return f.Close()
+}
+
+// Alerts returns copies of the alerts with an ID greater than since, oldest
+// first.
+func (w *Watcher) Alerts(since int) []*Alert {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	alerts := make([]*Alert, 0, len(w.alerts))
+	for _, a := range w.alerts {
+		if a.ID > since {
+			alert := *a
+			alerts = append(alerts, &alert)
+		}
+	}
+	return alerts
+}
+
+// ProcessBlock raises the alerts of the block outputs funded by the watched
+// sources. The alerts are saved and posted to the webhooks before they are
+// returned. An error is only returned if the alerts could not be saved. The
+// txs are checked in the block order thus funds moved more than once in the
+// block are followed too.
+func (w *Watcher) ProcessBlock(block *wire.MsgBlock) ([]*Alert, error) {
+	w.processMtx.Lock()
+	defer w.processMtx.Unlock()
+
+	txs := make([]*wire.MsgTx, 0, len(block.Transactions)+len(block.STransactions))
+	txs = append(txs, block.Transactions...)
+	txs = append(txs, block.STransactions...)
+
+	// pending holds the outputs alerted in the block until they are watched.
+	pending := make(map[string]string)
+
+	var alerts []*Alert
+	for i, msgTx := range txs {
+		// The first regular tree transaction is the coinbase.
+		if i == 0 {
+			continue
+		}
+
+		// A tx that cannot be checked does not stop the rest of the block
+		// from being checked.
+		txAlerts, err := w.checkTx(msgTx, pending)
+		if err != nil {
+			log.Errorf("Failed to check tx %s: %v", msgTx.TxHash(), err)
+			continue
+		}
+
+		for _, alert := range txAlerts {
+			alert.Time = time.Now().Unix()
+			alert.BlockHeight = int64(block.Header.Height)
+			alert.BlockHash = block.Header.BlockHash().String()
+
+			pending[outpointKey(alert.TxHash, alert.Vout)] = alert.Source
+			alerts = append(alerts, alert)
+		}
+	}
+
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+
+	if err := w.addAlerts(alerts); err != nil {
+		return nil, err
+	}
+
+	for _, alert := range alerts {
+		log.Infof("Alert %d: %v DCR of %s moved to %s:%d with a probability of %v",
+			alert.ID, alert.Amount, alert.Source, alert.TxHash, alert.Vout, alert.Probability)
+
+		w.notifier.notify(alert)
+	}
+
+	return alerts, nil
+}
+
+// addAlerts numbers the alerts, saves them and watches their outputs.
+func (w *Watcher) addAlerts(alerts []*Alert) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	for i, alert := range alerts {
+		alert.ID = len(w.alerts) + i + 1
+	}
+
+	if err := w.saveAlerts(alerts); err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		w.alerts = append(w.alerts, alert)
+		w.outpoints[outpointKey(alert.TxHash, alert.Vout)] = alert.Source
+	}
+	return nil
+}
+
+// watchedSource returns the watched source of the outpoint, the outputs
+// alerted in the block being processed included. An empty string is returned
+// if the outpoint is not watched.
+func (w *Watcher) watchedSource(op string, pending map[string]string) string {
+	if source, ok := pending[op]; ok {
+		return source
+	}
+
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.outpoints[op]
+}
+
+// watchedInput is a tx input spending a watched source.
+type watchedInput struct {
+	amount float64
+	source string
+	spent  string
+}
+
+// checkTx returns the alerts of the tx outputs funded by the watched inputs
+// with at least the minimum alert probability. pending holds the outputs
+// alerted in the block being processed.
+func (w *Watcher) checkTx(msgTx *wire.MsgTx, pending map[string]string) ([]*Alert, error) {
+	var watched []watchedInput
+	for _, in := range msgTx.TxIn {
+		prevOut := in.PreviousOutPoint
+		if prevOut.Hash == (chainhash.Hash{}) {
+			continue
+		}
+
+		spent := outpointKey(prevOut.Hash.String(), prevOut.Index)
+		source := w.watchedSource(spent, pending)
+		if source == "" {
+			var err error
+			source, err = w.spentAddress(prevOut)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if source != "" {
+			watched = append(watched, watchedInput{
+				amount: dcrutil.Amount(in.ValueIn).ToCoin(),
+				source: source,
+				spent:  spent,
+			})
+		}
+	}
+
+	if len(watched) == 0 {
+		return nil, nil
+	}
+
+	amountsIn := make([]float64, len(msgTx.TxIn))
+	for i, in := range msgTx.TxIn {
+		amountsIn[i] = dcrutil.Amount(in.ValueIn).ToCoin()
+	}
+
+	tx := rpcutils.ExtractMsgTxTransaction(msgTx, amountsIn, w.cfg.ActiveNet)
+	analysis, err := analytics.AnalyzeTransaction(tx)
+	if err != nil {
+		return nil, fmt.Errorf("analyzing tx %s failed: %v", tx.TxID, err)
+	}
+
+	if analysis.LinkMatrix == nil {
+		log.Warnf("Watched funds moved by tx %s could not be followed: %s", tx.TxID,
+			analysis.Solutions[0].StatusMsg)
+		return nil, nil
+	}
+
+	var alerts []*Alert
+	for _, out := range tx.Outpoints {
+		if out.Value <= 0 {
+			continue
+		}
+
+		var best watchedInput
+		var probability float64
+		for _, in := range watched {
+			if p := analysis.LinkMatrix.Probability(in.amount, out.Value); p > probability {
+				best, probability = in, p
+			}
+		}
+
+		if probability < w.cfg.MinProbability {
+			continue
+		}
+
+		alerts = append(alerts, &Alert{
+			TxHash:      tx.TxID,
+			Vout:        out.TxIndex,
+			Amount:      out.Value,
+			Addresses:   out.PkScriptData.Addresses,
+			Probability: probability,
+			Source:      best.source,
+			Spent:       best.spent,
+		})
+	}
+	return alerts, nil
+}
+
+// spentAddress returns the watched address the outpoint pays to. An empty
+// string is returned if no address is watched, if the outpoint does not pay
+// to a watched address or if the outpoint tx is not found.
+func (w *Watcher) spentAddress(prevOut wire.OutPoint) (string, error) {
+	if len(w.addresses) == 0 || w.client == nil {
+		return "", nil
+	}
+
+	prevTx, err := analytics.RetrieveTxData(w.client, prevOut.Hash.String())
+	if code, ok := rpcutils.ErrorCodeOf(err); ok && code == rpcutils.ErrTxNotFound {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, out := range prevTx.Outpoints {
+		if out.TxIndex != prevOut.Index {
+			continue
+		}
+
+		for _, addr := range out.PkScriptData.Addresses {
+			if w.addresses[addr] {
+				return addr, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// BlockConnected queues the block of the serialized header for processing. It
+// matches the dcrd OnBlockConnected notification handler signature and does
+// not block. The block is dropped if the queue is full.
+func (w *Watcher) BlockConnected(blockHeader []byte, _ [][]byte) {
+	var header wire.BlockHeader
+	if err := header.FromBytes(blockHeader); err != nil {
+		log.Errorf("Failed to decode the connected block header: %v", err)
+		return
+	}
+
+	select {
+	case w.blocks <- header.BlockHash():
+	default:
+		log.Errorf("Block %d (%s) dropped: the watchlist queue is full", header.Height,
+			header.BlockHash())
+	}
+}
+
+// Start processes the queued blocks fetched from the blocks source in a
+// goroutine till Stop is called.
+func (w *Watcher) Start(blocks rpcutils.BlockSource) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		for {
+			select {
+			case hash := <-w.blocks:
+				block, err := rpcutils.GetBlockByHash(blocks, &hash)
+				if err != nil {
+					log.Errorf("Failed to fetch the connected block: %v", err)
+					continue
+				}
+
+				if _, err = w.ProcessBlock(block.MsgBlock()); err != nil {
+					log.Errorf("Failed to check block %s: %v", hash, err)
+				}
+
+			case <-w.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the blocks processing and waits for the pending webhook
+// deliveries.
+func (w *Watcher) Stop() {
+	close(w.quit)
+	w.wg.Wait()
+	w.notifier.wait()
+}
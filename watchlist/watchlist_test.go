@@ -0,0 +1,329 @@
+package watchlist
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrjson"
+	"github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/wire"
+	"github.com/raedahgroup/dcrchainanalysis/v1/networkconfig"
+)
+
+const (
+	watchedTxHash = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	addressTxHash = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	unknownTxHash = "cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+)
+
+// mapSource is a TxSource that serves transactions from a map.
+type mapSource map[string]*dcrjson.TxRawResult
+
+func (m mapSource) GetRawTransactionVerbose(txHash *chainhash.Hash) (
+	*dcrjson.TxRawResult, error) {
+	tx, ok := m[txHash.String()]
+	if !ok {
+		return nil, &dcrjson.RPCError{Code: dcrjson.ErrRPCNoTxInfo,
+			Message: "No information available about transaction"}
+	}
+	return tx, nil
+}
+
+// input returns a tx input spending the outpoint of the amount.
+func input(txHash string, vout uint32, amount float64) *wire.TxIn {
+	hash, _ := chainhash.NewHashFromStr(txHash)
+	value, _ := dcrutil.NewAmount(amount)
+	return wire.NewTxIn(wire.NewOutPoint(hash, vout, wire.TxTreeRegular), int64(value), nil)
+}
+
+// newTx returns a tx with the inputs paying the amounts to public key hashes.
+func newTx(inputs []*wire.TxIn, amounts ...float64) *wire.MsgTx {
+	tx := wire.NewMsgTx()
+	for _, in := range inputs {
+		tx.AddTxIn(in)
+	}
+
+	for i, amount := range amounts {
+		script := make([]byte, 25)
+		copy(script, []byte{0x76, 0xa9, 0x14})
+		script[3] = byte(i + 1)
+		script[23], script[24] = 0x88, 0xac
+
+		value, _ := dcrutil.NewAmount(amount)
+		tx.AddTxOut(wire.NewTxOut(int64(value), script))
+	}
+	return tx
+}
+
+// newBlock returns a block at the height holding a coinbase and the txs.
+func newBlock(height uint32, txs ...*wire.MsgTx) *wire.MsgBlock {
+	coinbase := newTx([]*wire.TxIn{wire.NewTxIn(&wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		0, nil)}, 1)
+
+	block := &wire.MsgBlock{Header: wire.BlockHeader{Height: height}}
+	block.Transactions = append([]*wire.MsgTx{coinbase}, txs...)
+	return block
+}
+
+// webhookReceiver is a local webhook that fails the first delivery.
+type webhookReceiver struct {
+	mtx      sync.Mutex
+	attempts int
+	alerts   []*Alert
+}
+
+func (h *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.attempts++
+	if h.attempts == 1 {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	alert := new(Alert)
+	if err := json.NewDecoder(r.Body).Decode(alert); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.alerts = append(h.alerts, alert)
+}
+
+// TestWatcher tests that the watched funds are followed through the blocks,
+// that the alerts are saved and that they are posted to the webhooks.
+func TestWatcher(t *testing.T) {
+	type testData struct {
+		TxHash      string
+		Vout        uint32
+		Source      string
+		Spent       string
+		Probability float64
+	}
+
+	dir, err := ioutil.TempDir("", "watchlist")
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	receiver := new(webhookReceiver)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	client := mapSource{
+		addressTxHash: {
+			Txid: addressTxHash,
+			Vout: []dcrjson.Vout{
+				{Value: 1, N: 0, ScriptPubKey: dcrjson.ScriptPubKeyResult{
+					Type: "pubkeyhash", Addresses: []string{"TsOther"}}},
+				{Value: 2, N: 1, ScriptPubKey: dcrjson.ScriptPubKeyResult{
+					Type: "pubkeyhash", Addresses: []string{"TsWatched"}}},
+			},
+		},
+	}
+
+	watchedOutpoint := watchedTxHash + ":0"
+	cfg := Config{
+		Addresses:      []string{"TsWatched"},
+		Outpoints:      []string{watchedOutpoint},
+		MinProbability: 0.9,
+		AlertsFile:     filepath.Join(dir, "alerts.json"),
+		Webhooks:       []string{server.URL},
+		Retries:        2,
+		RetryInterval:  time.Millisecond,
+		ActiveNet:      networkconfig.TestNet,
+	}
+
+	w, err := New(cfg, client)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	// The unknown outpoint is neither watched nor found.
+	mixTx := newTx([]*wire.TxIn{input(watchedTxHash, 0, 5), input(unknownTxHash, 0, 3)},
+		4.99, 2.99)
+	addressTx := newTx([]*wire.TxIn{input(addressTxHash, 1, 2)}, 1.99)
+	cleanTx := newTx([]*wire.TxIn{input(addressTxHash, 0, 1)}, 0.99)
+
+	// The alerted output of mixTx moves again in the next block.
+	mixHash := mixTx.TxHash().String()
+	nextTx := newTx([]*wire.TxIn{input(mixHash, 0, 4.99)}, 4.98)
+
+	td := []testData{
+		{TxHash: mixHash, Vout: 0, Source: watchedOutpoint, Spent: watchedOutpoint,
+			Probability: 1},
+		{TxHash: addressTx.TxHash().String(), Vout: 0, Source: "TsWatched",
+			Spent: addressTxHash + ":1", Probability: 1},
+		{TxHash: nextTx.TxHash().String(), Vout: 0, Source: watchedOutpoint,
+			Spent: mixHash + ":0", Probability: 1},
+	}
+
+	var alerts []*Alert
+	for height, block := range []*wire.MsgBlock{
+		newBlock(1, mixTx, addressTx, cleanTx),
+		newBlock(2, nextTx),
+	} {
+		blockAlerts, err := w.ProcessBlock(block)
+		if err != nil {
+			t.Fatalf("expected no error but found: %v", err)
+		}
+
+		for _, alert := range blockAlerts {
+			if alert.BlockHeight != int64(height+1) {
+				t.Fatalf("expected the alert block height %d but found %d", height+1,
+					alert.BlockHeight)
+			}
+		}
+		alerts = append(alerts, blockAlerts...)
+	}
+
+	w.Stop()
+
+	if len(alerts) != len(td) {
+		t.Fatalf("expected %d alerts but found %d: %+v", len(td), len(alerts), alerts)
+	}
+
+	for i, data := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			a := alerts[i]
+			if a.ID != i+1 || a.TxHash != data.TxHash || a.Vout != data.Vout ||
+				a.Source != data.Source || a.Spent != data.Spent ||
+				a.Probability != data.Probability {
+				t.Fatalf("expected alert %d to match %+v but found %+v", i+1, data, a)
+			}
+		})
+	}
+
+	// The first delivery failed and was retried.
+	if receiver.attempts != len(td)+1 || len(receiver.alerts) != len(td) {
+		t.Fatalf("expected %d delivery attempts and %d alerts delivered but found %d and %d",
+			len(td)+1, len(td), receiver.attempts, len(receiver.alerts))
+	}
+
+	reloaded, err := New(Config{MinProbability: 0.9, AlertsFile: cfg.AlertsFile}, nil)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	if saved := reloaded.Alerts(1); len(saved) != len(td)-1 || saved[0].ID != 2 {
+		t.Fatalf("expected the alerts saved after alert 1 but found %+v", saved)
+	}
+
+	// The alerted outputs are watched once reloaded.
+	moved, err := reloaded.ProcessBlock(newBlock(3,
+		newTx([]*wire.TxIn{input(nextTx.TxHash().String(), 0, 4.98)}, 4.97)))
+	if err != nil || len(moved) != 1 || moved[0].ID != 4 || moved[0].Source != watchedOutpoint {
+		t.Fatalf("expected the reloaded alert output to be followed but found %+v, %v",
+			moved, err)
+	}
+}
+
+// TestNewErrors tests that the invalid watchlist configurations are rejected.
+func TestNewErrors(t *testing.T) {
+	td := []Config{
+		{MinProbability: 0},
+		{MinProbability: 1.5},
+		{MinProbability: 0.5, Outpoints: []string{watchedTxHash}},
+		{MinProbability: 0.5, Outpoints: []string{"abc:0"}},
+	}
+
+	for i, cfg := range td {
+		t.Run("Test_#"+strconv.Itoa(i), func(t *testing.T) {
+			if _, err := New(cfg, nil); err == nil || !strings.Contains(err.Error(), "invalid") {
+				t.Fatalf("expected an invalid config error but found %v", err)
+			}
+		})
+	}
+}
+
+// blockingSource is a TxSource that blocks each request until it is released.
+type blockingSource struct {
+	mapSource
+	called  chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingSource) GetRawTransactionVerbose(txHash *chainhash.Hash) (
+	*dcrjson.TxRawResult, error) {
+	b.called <- struct{}{}
+	<-b.release
+	return b.mapSource.GetRawTransactionVerbose(txHash)
+}
+
+// TestAlertsWhileProcessing tests that the alerts can be read while a block
+// is checked since the watchlist is not locked during the tx lookups.
+func TestAlertsWhileProcessing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watchlist")
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	client := &blockingSource{
+		mapSource: mapSource{
+			addressTxHash: {
+				Txid: addressTxHash,
+				Vout: []dcrjson.Vout{{Value: 2, N: 0, ScriptPubKey: dcrjson.ScriptPubKeyResult{
+					Type: "pubkeyhash", Addresses: []string{"TsWatched"}}}},
+			},
+		},
+		called:  make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	w, err := New(Config{
+		Addresses:      []string{"TsWatched"},
+		MinProbability: 0.9,
+		AlertsFile:     filepath.Join(dir, "alerts.json"),
+	}, client)
+	if err != nil {
+		t.Fatalf("expected no error but found: %v", err)
+	}
+
+	type result struct {
+		alerts []*Alert
+		err    error
+	}
+
+	done := make(chan result)
+	go func() {
+		alerts, err := w.ProcessBlock(newBlock(1,
+			newTx([]*wire.TxIn{input(addressTxHash, 0, 2)}, 1.99)))
+		done <- result{alerts, err}
+	}()
+
+	<-client.called
+
+	read := make(chan []*Alert)
+	go func() {
+		read <- w.Alerts(0)
+	}()
+
+	select {
+	case alerts := <-read:
+		if len(alerts) != 0 {
+			t.Fatalf("expected no alerts yet but found %+v", alerts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the alerts to be read while the block is checked")
+	}
+
+	close(client.release)
+
+	res := <-done
+	if res.err != nil || len(res.alerts) != 1 || res.alerts[0].ID != 1 {
+		t.Fatalf("expected a single alert but found %+v, %v", res.alerts, res.err)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2018, Migwi Ndung'u
+// See LICENSE for details.
+
+package watchlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// webhookTimeout is the maximum duration of a single webhook delivery attempt.
+const webhookTimeout = 10 * time.Second
+
+// notifier posts the alerts to the webhooks as JSON, retrying the failed
+// deliveries.
+type notifier struct {
+	urls     []string
+	retries  int
+	interval time.Duration
+	client   *http.Client
+	wg       sync.WaitGroup
+}
+
+// newNotifier returns a notifier of the webhooks URLs.
+func newNotifier(urls []string, retries int, interval time.Duration) *notifier {
+	if retries < 0 {
+		retries = 0
+	}
+
+	return &notifier{
+		urls:     urls,
+		retries:  retries,
+		interval: interval,
+		client:   &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// notify posts the alert to every webhook in the background.
+func (n *notifier) notify(alert *Alert) {
+	if len(n.urls) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		log.Errorf("Failed to encode the alert %d: %v", alert.ID, err)
+		return
+	}
+
+	for _, url := range n.urls {
+		n.wg.Add(1)
+		go func(url string) {
+			defer n.wg.Done()
+
+			if err := n.deliver(url, body); err != nil {
+				log.Errorf("Failed to deliver the alert %d to %s: %v", alert.ID, url, err)
+			}
+		}(url)
+	}
+}
+
+// deliver posts the body to the webhook till it is accepted with a 2xx status
+// or the retries are used up. The wait before each retry is doubled.
+func (n *notifier) deliver(url string, body []byte) (err error) {
+	wait := n.interval
+	for attempt := 0; attempt <= n.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+
+		if err = n.post(url, body); err == nil {
+			return nil
+		}
+
+		log.Debugf("Alert delivery attempt %d to %s failed: %v", attempt+1, url, err)
+	}
+	return err
+}
+
+// post sends a single delivery attempt.
+func (n *notifier) post(url string, body []byte) error {
+	resp, err := n.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// wait blocks till the pending deliveries complete.
+func (n *notifier) wait() {
+	n.wg.Wait()
+}